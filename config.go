@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/gabrielperezs/goreactor/lib"
+	"gopkg.in/yaml.v3"
+)
+
+// configExtensions are the file extensions accepted in the configuration
+// directory and the format used to decode each of them
+var configExtensions = map[string]string{
+	".conf": "toml",
+	".toml": "toml",
+	".yaml": "yaml",
+	".yml":  "yaml",
+	".json": "json",
+}
+
+// configFormat returns the format of the configuration file based on its
+// extension, false if the extension is not a known configuration format
+func configFormat(path string) (string, bool) {
+	format, ok := configExtensions[strings.ToLower(filepath.Ext(path))]
+	return format, ok
+}
+
+// decodeConfigFile reads one configuration file with the given format. YAML and
+// JSON are decoded to the same types the TOML parser produces, so the plugins
+// see identical values whatever format was used.
+func decodeConfigFile(path, format string) (*Config, error) {
+	c := &Config{}
+
+	if format == "toml" {
+		if _, err := toml.DecodeFile(path, c); err != nil {
+			return nil, err
+		}
+		return c, interpolateConfig(c)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw map[string]any
+	switch format {
+	case "yaml":
+		err = yaml.Unmarshal(b, &raw)
+	case "json":
+		d := json.NewDecoder(bytes.NewReader(b))
+		d.UseNumber() // Keep integers apart from floats, like TOML does
+		err = d.Decode(&raw)
+	default:
+		err = fmt.Errorf("unknown configuration format: %s", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for k, v := range raw {
+		if v, err = normalizeConfigValue(v); err != nil {
+			return nil, fmt.Errorf("%s: %w", k, err)
+		}
+		switch strings.ToLower(k) {
+		case "maxconcurrency":
+			n, ok := v.(int64)
+			if !ok {
+				return nil, fmt.Errorf("maxConcurrency must be an integer")
+			}
+			c.MaxConcurrency = int(n)
+		case "metricslisten":
+			s, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("metricsListen must be a string")
+			}
+			c.MetricsListen = s
+		case "logstream":
+			c.LogStream = v
+		case "reactor":
+			reactors, ok := v.([]any)
+			if !ok {
+				return nil, fmt.Errorf("reactor must be a list")
+			}
+			c.Reactor = append(c.Reactor, reactors...)
+		}
+	}
+	return c, interpolateConfig(c)
+}
+
+// interpolateConfig expands the ${env:NAME} and ${file:/path} references
+// in the logstream and reactors configuration
+func interpolateConfig(c *Config) (err error) {
+	if c.LogStream, err = lib.InterpolateConfig(c.LogStream); err != nil {
+		return err
+	}
+	for i, r := range c.Reactor {
+		if c.Reactor[i], err = lib.InterpolateConfig(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// normalizeConfigValue converts the values produced by the YAML and JSON
+// decoders to the ones produced by the TOML decoder: integers are int64,
+// tables are map[string]any and arrays are []any. TOML has no null nor
+// integers larger than int64, they are rejected.
+func normalizeConfigValue(v any) (any, error) {
+	var err error
+	switch t := v.(type) {
+	case nil:
+		return nil, fmt.Errorf("null values are not allowed")
+	case int:
+		return int64(t), nil
+	case uint64:
+		if t > math.MaxInt64 {
+			return nil, fmt.Errorf("the integer %d is too large", t)
+		}
+		return int64(t), nil
+	case json.Number:
+		if n, err := t.Int64(); err == nil {
+			return n, nil
+		}
+		if !strings.ContainsAny(t.String(), ".eE") {
+			return nil, fmt.Errorf("the integer %s is too large", t)
+		}
+		f, _ := t.Float64()
+		return f, nil
+	case map[string]any:
+		for k, nv := range t {
+			if t[k], err = normalizeConfigValue(nv); err != nil {
+				return nil, fmt.Errorf("%s: %w", k, err)
+			}
+		}
+		return t, nil
+	case map[any]any:
+		m := make(map[string]any, len(t))
+		for k, nv := range t {
+			if m[fmt.Sprint(k)], err = normalizeConfigValue(nv); err != nil {
+				return nil, fmt.Errorf("%v: %w", k, err)
+			}
+		}
+		return m, nil
+	case []any:
+		for i, nv := range t {
+			if t[i], err = normalizeConfigValue(nv); err != nil {
+				return nil, fmt.Errorf("%d: %w", i, err)
+			}
+		}
+		return t, nil
+	}
+	return v, nil
+}
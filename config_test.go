@@ -0,0 +1,91 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadConfigMixedFormats(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"a.conf": `
+maxConcurrency = 4
+
+[[reactor]]
+concurrent = 2
+input = "sqs"
+args = ["$.a"]
+`,
+		"b.yaml": `
+reactor:
+  - concurrent: 3
+    input: sqs
+    args: ["$.b"]
+    cond:
+      - "$.Event": "launch"
+`,
+		"c.json": `{"logstream": {"logstream": "stdout"}, "reactor": [{"concurrent": 5, "ratio": 1.5, "input": "sqs"}]}`,
+		"d.txt":  `ignored`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c, err := readConfig("", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, 4, c.MaxConcurrency)
+	assert.Equal(t, map[string]any{"logstream": "stdout"}, c.LogStream)
+	assert.Equal(t, 3, len(c.Reactor))
+
+	concurrent := make(map[int64]map[string]any)
+	for _, r := range c.Reactor {
+		m := r.(map[string]any)
+		concurrent[m["concurrent"].(int64)] = m
+	}
+
+	assert.Equal(t, []any{"$.a"}, concurrent[2]["args"])
+	assert.Equal(t, []any{"$.b"}, concurrent[3]["args"])
+	assert.Equal(t, []any{map[string]any{"$.Event": "launch"}}, concurrent[3]["cond"])
+	assert.Equal(t, 1.5, concurrent[5]["ratio"])
+}
+
+func TestReadConfigSingleFileByExtension(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+	if err := os.WriteFile(path, []byte("maxConcurrency: 2\nreactor:\n  - label: one\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := readConfig(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, 2, c.MaxConcurrency)
+	assert.Equal(t, []any{map[string]any{"label": "one"}}, c.Reactor)
+}
+
+func TestReadConfigRejectsNullAndOverflow(t *testing.T) {
+	dir := t.TempDir()
+	for name, tc := range map[string]struct{ content, err string }{
+		"null.yaml":     {"reactor:\n  - label: one\n    args:\n", "reactor: 0: args: null values are not allowed"},
+		"null.json":     {`{"reactor": [{"cmd": null}]}`, "reactor: 0: cmd: null values are not allowed"},
+		"overflow.yaml": {"reactor:\n  - concurrent: 18446744073709551615\n", "reactor: 0: concurrent: the integer 18446744073709551615 is too large"},
+		"overflow.json": {`{"maxConcurrency": 9223372036854775808}`, "maxConcurrency: the integer 9223372036854775808 is too large"},
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(tc.content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		_, err := readConfig(path, "")
+		assert.EqualError(t, err, tc.err, name)
+	}
+}
@@ -4,7 +4,6 @@ go 1.24
 
 require (
 	github.com/BurntSushi/toml v1.5.0
-	github.com/Jeffail/gabs v1.4.0
 	github.com/aws/aws-sdk-go v1.55.7
 	github.com/gabrielperezs/monad v0.0.0-20190930103133-261d32f2d7b2 // indirect
 	github.com/gabrielperezs/streamspooler v1.0.0
@@ -14,11 +13,11 @@ require (
 	github.com/golang/snappy v1.0.0 // indirect
 	github.com/savaki/jq v0.0.0-20161209013833-0e6baecebbf8
 	github.com/stretchr/testify v1.10.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/jmespath/go-jmespath v0.4.0 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -0,0 +1,120 @@
+package chain
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gabrielperezs/goreactor/lib"
+	"github.com/gabrielperezs/goreactor/reactor"
+)
+
+var (
+	mu       sync.RWMutex
+	registry = make(map[string]*Chain)
+)
+
+// Chain is the input of a reactor that receives the messages from other
+// reactors of the same process, by the name of the reactor
+type Chain struct {
+	sync.RWMutex
+	r       *reactor.Reactor
+	name    string
+	stopped bool
+}
+
+// NewOrGet registers the reactor with its name
+func NewOrGet(r *reactor.Reactor, c map[string]any) (*Chain, error) {
+	p := &Chain{
+		r: r,
+	}
+
+	for k, v := range c {
+		switch strings.ToLower(k) {
+		case "name":
+			p.name, _ = v.(string)
+		}
+	}
+
+	if p.name == "" {
+		return nil, fmt.Errorf("REACTOR ERROR: name not found or invalid")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := registry[p.name]; ok {
+		return nil, fmt.Errorf("REACTOR ERROR: there is another reactor with the name %s", p.name)
+	}
+	registry[p.name] = p
+	return p, nil
+}
+
+// Send delivers the message to the reactor with the name, blocking while the
+// reactor is busy, and waits until the reactor processed it. It returns an
+// error if the reactor failed.
+func Send(ctx context.Context, name string, msg lib.Msg) error {
+	mu.RLock()
+	p, ok := registry[name]
+	mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("reactor %s not found", name)
+	}
+	return p.send(ctx, msg)
+}
+
+func (p *Chain) send(ctx context.Context, msg lib.Msg) error {
+	m := newMsg(msg)
+	if err := p.r.MatchConditions(m); err != nil {
+		return err
+	}
+
+	p.RLock()
+	if p.stopped {
+		p.RUnlock()
+		return fmt.Errorf("reactor %s is stopped", p.name)
+	}
+	select {
+	case p.r.Ch <- m:
+	case <-ctx.Done():
+		p.RUnlock()
+		return ctx.Err()
+	}
+	p.RUnlock()
+
+	m.Wait()
+	if !m.ok {
+		return fmt.Errorf("reactor %s failed", p.name)
+	}
+	return nil
+}
+
+// Done keeps the result of the reactor for the sender
+func (p *Chain) Done(v lib.Msg, status bool) {
+	if m, ok := v.(*Msg); ok {
+		m.ok = status
+	}
+}
+
+// KeepAlive is not needed, the sender keeps alive the original message
+func (p *Chain) KeepAlive(ctx context.Context, t time.Duration, v lib.Msg) error {
+	return nil
+}
+
+// Stop rejects the new messages, it waits for the messages being delivered
+func (p *Chain) Stop() {
+	p.Lock()
+	defer p.Unlock()
+	p.stopped = true
+}
+
+// Exit removes the reactor from the registry
+func (p *Chain) Exit() {
+	p.Stop()
+	mu.Lock()
+	defer mu.Unlock()
+	if registry[p.name] == p {
+		delete(registry, p.name)
+	}
+}
@@ -0,0 +1,146 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gabrielperezs/goreactor/lib"
+	"github.com/gabrielperezs/goreactor/reactor"
+	"github.com/gabrielperezs/goreactor/reactorlog"
+	"github.com/gallir/dynsemaphore"
+	"github.com/stretchr/testify/assert"
+)
+
+type testMsg struct {
+	body []byte
+	hash string
+}
+
+func (m *testMsg) Body() []byte {
+	return m.body
+}
+
+func (m *testMsg) CreationTimestampMilliseconds() int64 {
+	return 0
+}
+
+func (m *testMsg) GetHash() string {
+	return m.hash
+}
+
+func (m *testMsg) Done() {
+}
+
+func (m *testMsg) Wait() {
+}
+
+// testOutput fails with the messages {"fail":true} and blocks until release
+// is closed
+type testOutput struct {
+	release chan struct{}
+	started chan string
+}
+
+func (o *testOutput) MatchConditions(msg lib.Msg) error {
+	return nil
+}
+
+func (o *testOutput) Run(ctx context.Context, rl reactorlog.ReactorLog, msg lib.Msg) error {
+	o.started <- msg.GetHash()
+	<-o.release
+	if string(msg.Body()) == `{"fail":true}` {
+		return errors.New("failed")
+	}
+	return nil
+}
+
+func (o *testOutput) Exit() {
+}
+
+func newTestReactor(t *testing.T, name string) (*reactor.Reactor, *testOutput) {
+	r := reactor.NewReactor(map[string]any{"concurrent": int64(1)})
+	out := &testOutput{release: make(chan struct{}), started: make(chan string, 10)}
+	r.O = out
+
+	var err error
+	r.I, err = NewOrGet(r, map[string]any{"name": name})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Start()
+	t.Cleanup(r.Exit)
+	return r, out
+}
+
+func TestChainSend(t *testing.T) {
+	_, out := newTestReactor(t, "target")
+	close(out.release)
+
+	assert.NoError(t, Send(context.Background(), "target", &testMsg{body: []byte(`{}`), hash: "h1"}))
+	assert.Equal(t, "h1", <-out.started)
+
+	err := Send(context.Background(), "target", &testMsg{body: []byte(`{"fail":true}`)})
+	assert.ErrorContains(t, err, "reactor target failed")
+
+	assert.ErrorContains(t, Send(context.Background(), "other", &testMsg{}), "not found")
+}
+
+func TestChainBackpressure(t *testing.T) {
+	_, out := newTestReactor(t, "busy")
+
+	first := make(chan error)
+	go func() {
+		first <- Send(context.Background(), "busy", &testMsg{body: []byte(`{}`), hash: "h1"})
+	}()
+	assert.Equal(t, "h1", <-out.started)
+
+	// The only listener is busy, the second message is not accepted
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, Send(ctx, "busy", &testMsg{body: []byte(`{}`), hash: "h2"}), context.DeadlineExceeded)
+
+	select {
+	case <-first:
+		t.Fatal("The first message finished before the reactor")
+	default:
+	}
+	close(out.release)
+	assert.NoError(t, <-first)
+}
+
+func TestChainWithoutGlobalSlot(t *testing.T) {
+	r, out := newTestReactor(t, "global")
+	close(out.release)
+
+	// The sender holds the only global slot while it waits
+	cc := dynsemaphore.New(1)
+	r.SetConcurrencyControl(cc)
+	cc.Access()
+	defer cc.Release()
+
+	done := make(chan error)
+	go func() {
+		done <- Send(context.Background(), "global", &testMsg{body: []byte(`{}`), hash: "h1"})
+	}()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("The reactor waits for a global slot")
+	}
+}
+
+func TestChainNames(t *testing.T) {
+	r, _ := newTestReactor(t, "unique")
+
+	_, err := NewOrGet(r, map[string]any{"name": "unique"})
+	assert.Error(t, err)
+
+	_, err = NewOrGet(r, map[string]any{})
+	assert.Error(t, err)
+
+	r.I.Stop()
+	assert.ErrorContains(t, Send(context.Background(), "unique", &testMsg{}), "stopped")
+}
@@ -0,0 +1,75 @@
+package chain
+
+import (
+	"github.com/gabrielperezs/goreactor/lib"
+)
+
+// Msg is a message sent by other reactor, the original message (or the result
+// of the command) with the handshake to wait for the result
+type Msg struct {
+	parent lib.Msg
+	ok     bool
+	done   chan struct{}
+}
+
+func newMsg(parent lib.Msg) *Msg {
+	return &Msg{
+		parent: parent,
+		done:   make(chan struct{}),
+	}
+}
+
+func (m *Msg) Body() []byte {
+	return m.parent.Body()
+}
+
+func (m *Msg) CreationTimestampMilliseconds() int64 {
+	return m.parent.CreationTimestampMilliseconds()
+}
+
+// GetHash returns the hash of the original message, to correlate the logs
+func (m *Msg) GetHash() string {
+	return m.parent.GetHash()
+}
+
+func (m *Msg) Done() {
+	close(m.done)
+}
+
+func (m *Msg) Wait() {
+	<-m.done
+}
+
+// Chained runs the message in the execution slot of the sender, that waits
+// for it
+func (m *Msg) Chained() bool {
+	return true
+}
+
+func (m *Msg) CorrelationID() string {
+	if c, ok := m.parent.(lib.MsgCorrelation); ok {
+		return c.CorrelationID()
+	}
+	return m.parent.GetHash()
+}
+
+func (m *Msg) Variable(name string) (string, bool) {
+	if v, ok := m.parent.(lib.MsgVariables); ok {
+		return v.Variable(name)
+	}
+	return "", false
+}
+
+func (m *Msg) EnvelopeValue(name string) (string, bool) {
+	if e, ok := m.parent.(lib.MsgEnvelope); ok {
+		return e.EnvelopeValue(name)
+	}
+	return "", false
+}
+
+func (m *Msg) Attribute(name string) (string, bool) {
+	if a, ok := m.parent.(lib.MsgAttributes); ok {
+		return a.Attribute(name)
+	}
+	return "", false
+}
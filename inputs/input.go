@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/gabrielperezs/goreactor/inputs/chain"
 	"github.com/gabrielperezs/goreactor/inputs/sqs"
 	"github.com/gabrielperezs/goreactor/lib"
 	"github.com/gabrielperezs/goreactor/reactor"
@@ -25,6 +26,8 @@ func Get(r *reactor.Reactor, cfg any) (lib.Input, error) {
 			switch strings.ToLower(v.(string)) {
 			case "sqs":
 				return sqs.NewOrGet(r, c)
+			case "reactor":
+				return chain.NewOrGet(r, c)
 			default:
 				return nil, fmt.Errorf("Plugin don't exists: %s", k)
 			}
@@ -0,0 +1,109 @@
+package sqs
+
+import (
+	"expvar"
+	"log"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+const (
+	defaultErrorBackoffMin         = time.Second
+	defaultErrorBackoffMax         = 5 * time.Minute
+	defaultCircuitBreakerThreshold = 5 // Consecutive errors to open the circuit
+
+	circuitClosed   = "closed"
+	circuitOpen     = "open"
+	circuitHalfOpen = "half-open"
+)
+
+// metrics of the SQS listeners, by URL, published in /debug/vars
+var metrics = expvar.NewMap("sqs")
+
+// breaker controls the receive errors with an exponential backoff with jitter,
+// so the listeners don't retry in lockstep. After threshold consecutive errors
+// the circuit is open, and every retry is a single half-open request until one
+// of them succeeds.
+type breaker struct {
+	sync.Mutex
+	url       string
+	min       time.Duration
+	max       time.Duration
+	threshold int
+	failures  int
+	state     string
+	vars      *expvar.Map
+}
+
+func newBreaker(url string, min, max time.Duration, threshold int) *breaker {
+	b := &breaker{
+		url:       url,
+		min:       min,
+		max:       max,
+		threshold: threshold,
+		vars:      new(expvar.Map).Init(),
+	}
+	metrics.Set(url, b.vars)
+	b.setState(circuitClosed)
+	return b
+}
+
+func (b *breaker) setState(state string) {
+	if b.state == state {
+		return
+	}
+	if b.state != "" {
+		log.Printf("SQS circuit %s for %s after %d errors", state, b.url, b.failures)
+	}
+	b.state = state
+	s := new(expvar.String)
+	s.Set(state)
+	b.vars.Set("circuit", s)
+}
+
+// failure registers a receive error and returns how long to wait before retrying
+func (b *breaker) failure() time.Duration {
+	b.Lock()
+	defer b.Unlock()
+
+	b.failures++
+	b.vars.Add("receiveErrors", 1)
+	b.vars.Add("consecutiveErrors", 1)
+	if b.failures >= b.threshold {
+		b.setState(circuitOpen)
+	}
+	return b.delay()
+}
+
+// delay is the exponential backoff, with a random jitter between half
+// and the full delay
+func (b *breaker) delay() time.Duration {
+	d := b.max
+	if shift := b.failures - 1; shift < 32 && b.min<<shift < b.max {
+		d = b.min << shift
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// retry is called before a new request, the first one after the circuit was
+// open is the half-open test
+func (b *breaker) retry() {
+	b.Lock()
+	defer b.Unlock()
+	if b.state == circuitOpen {
+		b.setState(circuitHalfOpen)
+	}
+}
+
+// success closes the circuit
+func (b *breaker) success() {
+	b.Lock()
+	defer b.Unlock()
+	if b.failures == 0 {
+		return
+	}
+	b.setState(circuitClosed)
+	b.failures = 0
+	b.vars.Set("consecutiveErrors", new(expvar.Int))
+}
@@ -0,0 +1,33 @@
+package sqs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBreaker(t *testing.T) {
+	b := newBreaker("https://sqs.eu-west-1.amazonaws.com/1/backoff", time.Second, 10*time.Second, 3)
+
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second}
+	for i, max := range expected {
+		b.retry()
+		d := b.failure()
+		assert.GreaterOrEqual(t, d, max/2, i)
+		assert.LessOrEqual(t, d, max, i)
+		if i < 2 {
+			assert.Equal(t, circuitClosed, b.state)
+		} else {
+			assert.Equal(t, circuitOpen, b.state)
+		}
+	}
+
+	b.retry()
+	assert.Equal(t, circuitHalfOpen, b.state)
+	b.success()
+	assert.Equal(t, circuitClosed, b.state)
+	assert.Equal(t, 0, b.failures)
+	assert.Equal(t, `"closed"`, b.vars.Get("circuit").String())
+	assert.Equal(t, "6", b.vars.Get("receiveErrors").String())
+}
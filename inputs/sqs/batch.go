@@ -0,0 +1,221 @@
+package sqs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+)
+
+const (
+	maxBatchEntries      = 10 // Limit of entries of the SQS batch requests
+	maxBatchRetries      = 2  // Retries of the entries that failed by a SQS error
+	defaultBatchInterval = 100 * time.Millisecond
+)
+
+// batchEntry is a delete or a visibility change of a message
+type batchEntry struct {
+	receiptHandle *string
+	visibility    int64      // Seconds, only for visibility changes
+	result        chan error // Optional, receives the result of the entry
+	retries       int
+}
+
+func (e *batchEntry) done(err error) {
+	if e.result != nil {
+		e.result <- err
+	}
+}
+
+// batchQueue accumulates the entries of one kind of request until there are
+// maxBatchEntries or the interval expires
+type batchQueue struct {
+	entries []*batchEntry
+	timer   *time.Timer
+	send    func(entries []*batchEntry)
+}
+
+// batcher coalesces the deletes and visibility changes of the messages
+// of a queue in DeleteMessageBatch and ChangeMessageVisibilityBatch requests
+type batcher struct {
+	sync.Mutex
+	url      string
+	svc      sqsiface.SQSAPI
+	interval time.Duration
+	closed   bool
+	wg       sync.WaitGroup
+
+	deletes *batchQueue
+	changes *batchQueue
+}
+
+func newBatcher(svc sqsiface.SQSAPI, url string, interval time.Duration) *batcher {
+	if interval <= 0 {
+		interval = defaultBatchInterval
+	}
+	b := &batcher{
+		url:      url,
+		svc:      svc,
+		interval: interval,
+	}
+	b.deletes = &batchQueue{send: b.deleteBatch}
+	b.changes = &batchQueue{send: b.changeVisibilityBatch}
+	return b
+}
+
+// Delete adds the message to the next delete batch
+func (b *batcher) Delete(receiptHandle *string) {
+	b.add(b.deletes, &batchEntry{receiptHandle: receiptHandle})
+}
+
+// ChangeVisibility adds the message to the next visibility change batch and
+// waits for its result
+func (b *batcher) ChangeVisibility(ctx context.Context, receiptHandle *string, sec int64) error {
+	e := &batchEntry{
+		receiptHandle: receiptHandle,
+		visibility:    sec,
+		result:        make(chan error, 1),
+	}
+	b.add(b.changes, e)
+
+	select {
+	case err := <-e.result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (b *batcher) add(q *batchQueue, e *batchEntry) {
+	b.Lock()
+	defer b.Unlock()
+
+	if b.closed {
+		b.send(q, []*batchEntry{e})
+		return
+	}
+
+	q.entries = append(q.entries, e)
+	if len(q.entries) >= maxBatchEntries {
+		b.flush(q)
+		return
+	}
+	if q.timer == nil {
+		q.timer = time.AfterFunc(b.interval, func() {
+			b.Lock()
+			defer b.Unlock()
+			b.flush(q)
+		})
+	}
+}
+
+// flush sends the pending entries of the queue, it must be called with the lock
+func (b *batcher) flush(q *batchQueue) {
+	if q.timer != nil {
+		q.timer.Stop()
+		q.timer = nil
+	}
+	if len(q.entries) == 0 {
+		return
+	}
+	entries := q.entries
+	q.entries = nil
+	b.send(q, entries)
+}
+
+func (b *batcher) send(q *batchQueue, entries []*batchEntry) {
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		q.send(entries)
+	}()
+}
+
+// Close sends the pending entries and waits for the requests in flight. Later
+// entries are sent without waiting for others.
+func (b *batcher) Close() {
+	b.Lock()
+	b.closed = true
+	b.flush(b.deletes)
+	b.flush(b.changes)
+	b.Unlock()
+	b.wg.Wait()
+}
+
+func (b *batcher) deleteBatch(entries []*batchEntry) {
+	input := &sqs.DeleteMessageBatchInput{
+		QueueUrl: aws.String(b.url),
+	}
+	for i, e := range entries {
+		input.Entries = append(input.Entries, &sqs.DeleteMessageBatchRequestEntry{
+			Id:            aws.String(strconv.Itoa(i)),
+			ReceiptHandle: e.receiptHandle,
+		})
+	}
+
+	out, err := b.svc.DeleteMessageBatch(input)
+	if err != nil {
+		log.Printf("ERROR: %s - delete batch of %d messages: %s", b.url, len(entries), err)
+		for _, e := range entries {
+			e.done(err)
+		}
+		return
+	}
+
+	b.complete(b.deletes, entries, out.Failed)
+}
+
+func (b *batcher) changeVisibilityBatch(entries []*batchEntry) {
+	input := &sqs.ChangeMessageVisibilityBatchInput{
+		QueueUrl: aws.String(b.url),
+	}
+	for i, e := range entries {
+		input.Entries = append(input.Entries, &sqs.ChangeMessageVisibilityBatchRequestEntry{
+			Id:                aws.String(strconv.Itoa(i)),
+			ReceiptHandle:     e.receiptHandle,
+			VisibilityTimeout: aws.Int64(e.visibility),
+		})
+	}
+
+	out, err := b.svc.ChangeMessageVisibilityBatch(input)
+	if err != nil {
+		log.Printf("ERROR: %s - visibility batch of %d messages: %s", b.url, len(entries), err)
+		for _, e := range entries {
+			e.done(err)
+		}
+		return
+	}
+
+	b.complete(b.changes, entries, out.Failed)
+}
+
+// complete sends the result to every entry of a batch. The entries that failed
+// by an error of SQS, not of the request, are retried in the next batch.
+func (b *batcher) complete(q *batchQueue, entries []*batchEntry, failed []*sqs.BatchResultErrorEntry) {
+	failures := make(map[string]*sqs.BatchResultErrorEntry, len(failed))
+	for _, f := range failed {
+		failures[aws.StringValue(f.Id)] = f
+	}
+
+	for i, e := range entries {
+		f, ok := failures[strconv.Itoa(i)]
+		if !ok {
+			e.done(nil)
+			continue
+		}
+		if !aws.BoolValue(f.SenderFault) && e.retries < maxBatchRetries {
+			e.retries++
+			b.add(q, e)
+			continue
+		}
+		err := fmt.Errorf("%s: %s", aws.StringValue(f.Code), aws.StringValue(f.Message))
+		log.Printf("ERROR: %s - %s", b.url, err)
+		e.done(err)
+	}
+}
@@ -0,0 +1,110 @@
+package sqs
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeSQS struct {
+	sqsiface.SQSAPI
+	mu       sync.Mutex
+	deletes  [][]string
+	changes  [][]string
+	sent     []*sqs.SendMessageInput
+	failOnce map[string]bool // Receipt handles that fail with a SQS error the first time
+}
+
+func (f *fakeSQS) failed(handle string, id *string) []*sqs.BatchResultErrorEntry {
+	if !f.failOnce[handle] {
+		return nil
+	}
+	delete(f.failOnce, handle)
+	return []*sqs.BatchResultErrorEntry{{Id: id, Code: aws.String("InternalError"), SenderFault: aws.Bool(false)}}
+}
+
+func (f *fakeSQS) DeleteMessageBatch(in *sqs.DeleteMessageBatchInput) (*sqs.DeleteMessageBatchOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := &sqs.DeleteMessageBatchOutput{}
+	var handles []string
+	for _, e := range in.Entries {
+		handles = append(handles, *e.ReceiptHandle)
+		out.Failed = append(out.Failed, f.failed(*e.ReceiptHandle, e.Id)...)
+	}
+	f.deletes = append(f.deletes, handles)
+	return out, nil
+}
+
+func (f *fakeSQS) ChangeMessageVisibilityBatch(in *sqs.ChangeMessageVisibilityBatchInput) (*sqs.ChangeMessageVisibilityBatchOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := &sqs.ChangeMessageVisibilityBatchOutput{}
+	var handles []string
+	for _, e := range in.Entries {
+		handles = append(handles, *e.ReceiptHandle)
+		if *e.ReceiptHandle == "invalid" {
+			out.Failed = append(out.Failed, &sqs.BatchResultErrorEntry{Id: e.Id, Code: aws.String("ReceiptHandleIsInvalid"), SenderFault: aws.Bool(true)})
+		}
+	}
+	f.changes = append(f.changes, handles)
+	return out, nil
+}
+
+func TestBatcherDeletes(t *testing.T) {
+	f := &fakeSQS{failOnce: map[string]bool{"m3": true}}
+	b := newBatcher(f, "https://sqs.eu-west-1.amazonaws.com/1/batch", time.Hour)
+
+	for i := range 12 {
+		b.Delete(aws.String(fmt.Sprintf("m%d", i)))
+	}
+
+	// The first ten are sent by size, the other two and the retry when closing
+	assert.Eventually(t, func() bool {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		return len(f.deletes) == 1
+	}, time.Second, 10*time.Millisecond)
+	b.Close()
+
+	assert.Equal(t, 10, len(f.deletes[0]))
+	var all []string
+	for _, d := range f.deletes {
+		all = append(all, d...)
+	}
+	assert.Equal(t, 13, len(all))
+	assert.Contains(t, all[10:], "m3")
+}
+
+func TestBatcherChangeVisibility(t *testing.T) {
+	f := &fakeSQS{}
+	b := newBatcher(f, "https://sqs.eu-west-1.amazonaws.com/1/batch", 100*time.Millisecond)
+	defer b.Close()
+
+	var wg sync.WaitGroup
+	errs := make(map[string]error)
+	var mu sync.Mutex
+	for _, h := range []string{"a", "invalid", "b"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := b.ChangeVisibility(context.Background(), aws.String(h), 30)
+			mu.Lock()
+			errs[h] = err
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1, len(f.changes))
+	assert.NoError(t, errs["a"])
+	assert.NoError(t, errs["b"])
+	assert.Error(t, errs["invalid"])
+}
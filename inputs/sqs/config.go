@@ -0,0 +1,153 @@
+package sqs
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/gabrielperezs/goreactor/lib/awssession"
+)
+
+// listenConfig contains the settings of a queue listener. The listener is
+// shared by all the reactors with the same URL, so all of them must define
+// the same settings.
+type listenConfig struct {
+	awssession.Options
+	maxNumberOfMessages     int64
+	waitTimeSeconds         int64
+	visibilityTimeout       int64    // Seconds, 0 to use the queue default
+	attributeNames          []string // Attributes requested with the messages
+	messageAttributeNames   []string // Message attributes requested with the messages
+	batchInterval           time.Duration
+	errorBackoffMin         time.Duration
+	errorBackoffMax         time.Duration
+	circuitBreakerThreshold int
+	noBlocking              bool   // If true, the input will not block waiting for a reactor to finish
+	envelope                string // Envelope of the messages: sns, eventbridge, auto or none
+	s3Records               bool   // If true, S3 event notifications are executed once per record
+}
+
+func newListenConfig(c map[string]any) (listenConfig, error) {
+	cfg := listenConfig{
+		maxNumberOfMessages:     defaultMaxNumberOfMessages,
+		waitTimeSeconds:         defaultWaitTimeSeconds,
+		attributeNames:          []string{MessageSystemAttributeNameSentTimestamp},
+		messageAttributeNames:   []string{sqs.QueueAttributeNameAll},
+		batchInterval:           defaultBatchInterval,
+		errorBackoffMin:         defaultErrorBackoffMin,
+		errorBackoffMax:         defaultErrorBackoffMax,
+		circuitBreakerThreshold: defaultCircuitBreakerThreshold,
+		envelope:                envelopeSNS, // The EventBridge events are unwrapped only if enabled
+	}
+
+	for k, v := range c {
+		switch strings.ToLower(k) {
+		case "maxnumberofmessages":
+			cfg.maxNumberOfMessages, _ = v.(int64)
+		case "noblocking":
+			cfg.noBlocking, _ = v.(bool)
+		case "waittimeseconds":
+			cfg.waitTimeSeconds, _ = v.(int64)
+		case "visibilitytimeout":
+			d, err := time.ParseDuration(v.(string))
+			if err != nil {
+				return cfg, fmt.Errorf("SQS ERROR: invalid visibilityTimeout: %w", err)
+			}
+			cfg.visibilityTimeout = int64(math.Ceil(d.Seconds()))
+		case "attributenames":
+			for _, n := range v.([]any) {
+				if n.(string) != MessageSystemAttributeNameSentTimestamp {
+					cfg.attributeNames = append(cfg.attributeNames, n.(string))
+				}
+			}
+		case "messageattributenames":
+			cfg.messageAttributeNames = nil
+			for _, n := range v.([]any) {
+				cfg.messageAttributeNames = append(cfg.messageAttributeNames, n.(string))
+			}
+		case "errorbackoffmin", "errorbackoffmax":
+			d, err := time.ParseDuration(v.(string))
+			if err != nil || d <= 0 {
+				return cfg, fmt.Errorf("SQS ERROR: invalid %s: %v", k, v)
+			}
+			if strings.ToLower(k) == "errorbackoffmin" {
+				cfg.errorBackoffMin = d
+			} else {
+				cfg.errorBackoffMax = d
+			}
+		case "batchinterval":
+			var err error
+			if cfg.batchInterval, err = time.ParseDuration(v.(string)); err != nil {
+				return cfg, fmt.Errorf("SQS ERROR: invalid batchInterval: %w", err)
+			}
+		case "circuitbreakerthreshold":
+			n, _ := v.(int64)
+			cfg.circuitBreakerThreshold = int(n)
+		case "s3records":
+			cfg.s3Records, _ = v.(bool)
+		case "envelope":
+			cfg.envelope, _ = v.(string)
+			cfg.envelope = strings.ToLower(cfg.envelope)
+		default:
+			cfg.Options.Set(k, v)
+		}
+	}
+
+	if err := validEnvelope(cfg.envelope); err != nil {
+		return cfg, err
+	}
+
+	if cfg.waitTimeSeconds < 0 || cfg.waitTimeSeconds > 20 {
+		return cfg, fmt.Errorf("SQS ERROR: waitTimeSeconds must be between 0 and 20")
+	}
+
+	if cfg.errorBackoffMax < cfg.errorBackoffMin {
+		cfg.errorBackoffMax = cfg.errorBackoffMin
+	}
+	if cfg.circuitBreakerThreshold <= 0 {
+		cfg.circuitBreakerThreshold = defaultCircuitBreakerThreshold
+	}
+	if cfg.maxNumberOfMessages <= 0 {
+		cfg.maxNumberOfMessages = defaultMaxNumberOfMessages
+	}
+
+	if cfg.Region == "" {
+		return cfg, fmt.Errorf("SQS ERROR: Region not found or invalid")
+	}
+	return cfg, nil
+}
+
+// conflicts returns the names of the settings that are different, the values
+// are not returned because some of them are credentials
+func (cfg listenConfig) conflicts(o listenConfig) []string {
+	var names []string
+	check := func(name string, equal bool) {
+		if !equal {
+			names = append(names, name)
+		}
+	}
+
+	check("region", cfg.Region == o.Region)
+	check("profile", cfg.Profile == o.Profile)
+	check("endpoint", cfg.Endpoint == o.Endpoint)
+	check("accessKey", cfg.AccessKey == o.AccessKey)
+	check("secretKey", cfg.SecretKey == o.SecretKey)
+	check("roleArn", cfg.RoleArn == o.RoleArn)
+	check("disableSSL", cfg.DisableSSL == o.DisableSSL)
+	check("maxNumberOfMessages", cfg.maxNumberOfMessages == o.maxNumberOfMessages)
+	check("waitTimeSeconds", cfg.waitTimeSeconds == o.waitTimeSeconds)
+	check("visibilityTimeout", cfg.visibilityTimeout == o.visibilityTimeout)
+	check("attributeNames", slices.Equal(cfg.attributeNames, o.attributeNames))
+	check("messageAttributeNames", slices.Equal(cfg.messageAttributeNames, o.messageAttributeNames))
+	check("batchInterval", cfg.batchInterval == o.batchInterval)
+	check("errorBackoffMin", cfg.errorBackoffMin == o.errorBackoffMin)
+	check("errorBackoffMax", cfg.errorBackoffMax == o.errorBackoffMax)
+	check("circuitBreakerThreshold", cfg.circuitBreakerThreshold == o.circuitBreakerThreshold)
+	check("noBlocking", cfg.noBlocking == o.noBlocking)
+	check("envelope", cfg.envelope == o.envelope)
+	check("s3Records", cfg.s3Records == o.s3Records)
+	return names
+}
@@ -0,0 +1,77 @@
+package sqs
+
+import (
+	"testing"
+
+	"github.com/gabrielperezs/goreactor/reactor"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListenConfigConflicts(t *testing.T) {
+	a, err := newListenConfig(map[string]any{"region": "eu-west-1", "maxNumberOfMessages": int64(5), "secretKey": "s", "accessKey": "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := newListenConfig(map[string]any{"Region": "eu-west-1", "MaxNumberOfMessages": int64(5), "secretKey": "s", "accessKey": "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, a.conflicts(b))
+
+	c, err := newListenConfig(map[string]any{"region": "eu-west-1", "noBlocking": true, "secretKey": "other", "accessKey": "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{"secretKey", "maxNumberOfMessages", "noBlocking"}, a.conflicts(c))
+}
+
+func TestSharedListenReferences(t *testing.T) {
+	url := "http://127.0.0.1:1/000000000000/shared"
+	cfg := map[string]any{
+		"url":       url,
+		"region":    "elasticmq",
+		"endpoint":  "http://127.0.0.1:1",
+		"accessKey": "x",
+		"secretKey": "x",
+	}
+
+	r1 := reactor.NewReactor(map[string]any{"concurrent": int64(1)})
+	r2 := reactor.NewReactor(map[string]any{"concurrent": int64(2)})
+	r3 := reactor.NewReactor(map[string]any{})
+
+	p1, err := NewOrGet(r1, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p2, err := NewOrGet(r2, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := p1.queues[0].l
+	assert.Same(t, l, p2.queues[0].l)
+	assert.Equal(t, 2, l.refs)
+
+	conflicting := map[string]any{}
+	for k, v := range cfg {
+		conflicting[k] = v
+	}
+	conflicting["noBlocking"] = true
+	_, err = NewOrGet(r3, conflicting)
+	assert.ErrorContains(t, err, "noBlocking")
+	assert.Equal(t, 2, l.refs)
+
+	// The first reactor exits, the listener keeps running for the second one
+	p1.Stop()
+	p1.Exit()
+	assert.Equal(t, 1, l.refs)
+	assert.True(t, l.hasSubscribers())
+	nl, ok := connPool.Load(url)
+	assert.True(t, ok)
+	assert.Same(t, l, nl)
+
+	p2.Stop()
+	p2.Exit()
+	assert.Equal(t, 0, l.refs)
+	_, ok = connPool.Load(url)
+	assert.False(t, ok)
+}
@@ -0,0 +1,107 @@
+package sqs
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	envelopeAuto        = "auto"
+	envelopeNone        = "none"
+	envelopeSNS         = "sns"
+	envelopeEventBridge = "eventbridge"
+)
+
+func validEnvelope(s string) error {
+	switch s {
+	case envelopeAuto, envelopeNone, envelopeSNS, envelopeEventBridge:
+		return nil
+	}
+	return fmt.Errorf("SQS ERROR: unknown envelope %s", s)
+}
+
+// snsEnvelope is the body of the messages delivered by SNS to SQS
+// without raw message delivery
+type snsEnvelope struct {
+	Type              string
+	MessageId         string
+	TopicArn          string
+	Subject           string
+	Message           *string
+	Timestamp         string
+	MessageAttributes map[string]struct {
+		Type  string
+		Value string
+	}
+}
+
+// eventBridgeEnvelope is the body of the events delivered by EventBridge to SQS
+type eventBridgeEnvelope struct {
+	ID         string          `json:"id"`
+	DetailType string          `json:"detail-type"`
+	Source     string          `json:"source"`
+	Account    string          `json:"account"`
+	Time       string          `json:"time"`
+	Region     string          `json:"region"`
+	Detail     json.RawMessage `json:"detail"`
+}
+
+// unwrapEnvelope returns the inner message and the envelope metadata. If the
+// body is not in the expected envelope it's returned as it is.
+func unwrapEnvelope(kind string, b []byte) ([]byte, map[string]string) {
+	switch kind {
+	case envelopeSNS:
+		return unwrapSNS(b)
+	case envelopeEventBridge:
+		return unwrapEventBridge(b)
+	case envelopeAuto:
+		if body, meta := unwrapSNS(b); meta != nil {
+			return body, meta
+		}
+		return unwrapEventBridge(b)
+	}
+	return b, nil
+}
+
+func unwrapSNS(b []byte) ([]byte, map[string]string) {
+	e := &snsEnvelope{}
+	if err := json.Unmarshal(b, e); err != nil {
+		return b, nil
+	}
+	if e.Type != "Notification" || e.TopicArn == "" || e.Message == nil {
+		return b, nil
+	}
+
+	meta := map[string]string{
+		"Type":      e.Type,
+		"MessageId": e.MessageId,
+		"TopicArn":  e.TopicArn,
+		"Subject":   e.Subject,
+		"Timestamp": e.Timestamp,
+	}
+	for k, v := range e.MessageAttributes {
+		meta["MessageAttributes."+k] = v.Value
+	}
+	return []byte(*e.Message), meta
+}
+
+func unwrapEventBridge(b []byte) ([]byte, map[string]string) {
+	e := &eventBridgeEnvelope{}
+	if err := json.Unmarshal(b, e); err != nil {
+		return b, nil
+	}
+	if e.DetailType == "" || e.Source == "" || len(e.Detail) == 0 {
+		return b, nil
+	}
+
+	meta := map[string]string{
+		"id":          e.ID,
+		"detail-type": e.DetailType,
+		"source":      e.Source,
+		"account":     e.Account,
+		"time":        e.Time,
+		"region":      e.Region,
+	}
+	return []byte(strings.TrimSpace(string(e.Detail))), meta
+}
@@ -0,0 +1,58 @@
+package sqs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnwrapSNS(t *testing.T) {
+	b := []byte(`{"Type":"Notification","MessageId":"1","TopicArn":"arn:aws:sns:eu-west-1:9999999999:events","Subject":"launch",` +
+		`"Message":"{\"path\":\"C:\\\\tmp\",\"name\":\"caf\u00e9\"}","MessageAttributes":{"stage":{"Type":"String","Value":"prod"}}}`)
+
+	body, meta := unwrapEnvelope(envelopeAuto, b)
+	assert.Equal(t, `{"path":"C:\\tmp","name":"café"}`, string(body))
+	assert.Equal(t, "arn:aws:sns:eu-west-1:9999999999:events", meta["TopicArn"])
+	assert.Equal(t, "launch", meta["Subject"])
+	assert.Equal(t, "prod", meta["MessageAttributes.stage"])
+
+	body, meta = unwrapEnvelope(envelopeNone, b)
+	assert.Equal(t, b, body)
+	assert.Nil(t, meta)
+}
+
+func TestUnwrapEventBridge(t *testing.T) {
+	b := []byte(`{"id":"7","detail-type":"EC2 Instance State-change Notification","source":"aws.ec2","detail":{"state":"running"}}`)
+
+	body, meta := unwrapEnvelope(envelopeAuto, b)
+	assert.Equal(t, `{"state":"running"}`, string(body))
+	assert.Equal(t, "aws.ec2", meta["source"])
+	assert.Equal(t, "EC2 Instance State-change Notification", meta["detail-type"])
+
+	body, meta = unwrapEnvelope(envelopeSNS, b)
+	assert.Equal(t, b, body)
+	assert.Nil(t, meta)
+}
+
+func TestUnwrapPlainMessageKey(t *testing.T) {
+	b := []byte(`{"Message":"hello","Level":"info"}`)
+
+	body, meta := unwrapEnvelope(envelopeAuto, b)
+	assert.Equal(t, b, body)
+	assert.Nil(t, meta)
+}
+
+func TestDefaultEnvelope(t *testing.T) {
+	// The EventBridge events keep the body as it is received unless enabled
+	cfg, err := newListenConfig(map[string]any{"region": "eu-west-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, envelopeSNS, cfg.envelope)
+
+	cfg, err = newListenConfig(map[string]any{"region": "eu-west-1", "envelope": "EventBridge"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, envelopeEventBridge, cfg.envelope)
+}
@@ -2,16 +2,22 @@ package sqs
 
 import (
 	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
 )
 
 // Msg is the message struct that were captured by the input plugin
 type Msg struct {
 	URL           *string
-	SQS           *sqs.SQS
+	SQS           sqsiface.SQSAPI
 	M             *sqs.DeleteMessageBatchRequestEntry
 	B             []byte
+	Raw           []byte
+	Attributes    map[string]string
+	Envelope      map[string]string
+	Variables     map[string]string
 	SentTimestamp int64
 	Hash          string
+	listener      *sqsListen
 	doneCh        chan struct{}
 }
 
@@ -25,10 +31,41 @@ func (m *Msg) CreationTimestampMilliseconds() int64 {
 	return m.SentTimestamp
 }
 
+// RawBody will return the bytes of the SQS message before removing the envelope
+func (m *Msg) RawBody() []byte {
+	return m.Raw
+}
+
+// Attribute will return the value of a SQS message attribute
+func (m *Msg) Attribute(name string) (string, bool) {
+	v, ok := m.Attributes[name]
+	return v, ok
+}
+
+// EnvelopeValue will return a field of the envelope that contained the message
+func (m *Msg) EnvelopeValue(name string) (string, bool) {
+	v, ok := m.Envelope[name]
+	return v, ok
+}
+
+// Variable will return a variable defined by the input for this message
+func (m *Msg) Variable(name string) (string, bool) {
+	v, ok := m.Variables[name]
+	return v, ok
+}
+
 func (m *Msg) GetHash() string {
 	return m.Hash
 }
 
+// clone returns a copy of the message for one reactor, every reactor
+// signals when it's done with its own copy
+func (m *Msg) clone() *Msg {
+	c := *m
+	c.doneCh = make(chan struct{})
+	return &c
+}
+
 func (m *Msg) Done() {
 	close(m.doneCh)
 }
@@ -0,0 +1,71 @@
+package sqs
+
+import (
+	"context"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/gabrielperezs/goreactor/lib"
+)
+
+const outcomeTimeout = 10 * time.Second
+
+// DoneOutcome executes the action decided for the message by the exit code
+// of the command, and removes it from the pending queue
+func (p *sqsListen) DoneOutcome(m lib.Msg, o lib.Outcome) {
+	msg, ok := m.(*Msg)
+	if !ok {
+		return
+	}
+
+	switch o.Action {
+	case lib.ActionAck:
+		p.Done(m, true)
+	case lib.ActionRetry:
+		if o.Delay > 0 {
+			ctx, cancel := context.WithTimeout(context.Background(), outcomeTimeout)
+			sec := int64(math.Ceil(o.Delay.Seconds()))
+			if err := p.batcher.ChangeVisibility(ctx, msg.M.ReceiptHandle, sec); err != nil {
+				log.Printf("ERROR: %s - retry delay of %s: %s", p.url, msg.Hash, err)
+			}
+			cancel()
+		}
+		p.Done(m, false)
+	case lib.ActionDeadLetter:
+		// Sent once, when the executions of all the clones of the message finish
+		p.finish(m, true, &o)
+	default:
+		p.Done(m, false)
+	}
+}
+
+// deadLetter sends the message as it was received, with its attributes, to
+// the dead-letter queue
+func (p *sqsListen) deadLetter(m *Msg, queue string) error {
+	input := &sqs.SendMessageInput{
+		QueueUrl:    aws.String(queue),
+		MessageBody: aws.String(string(m.Raw)),
+	}
+	if strings.HasSuffix(queue, ".fifo") {
+		input.MessageGroupId = aws.String(m.Hash)
+		input.MessageDeduplicationId = aws.String(m.Hash)
+	}
+	if len(m.Attributes) > 0 {
+		input.MessageAttributes = make(map[string]*sqs.MessageAttributeValue, len(m.Attributes))
+		for k, v := range m.Attributes {
+			input.MessageAttributes[k] = &sqs.MessageAttributeValue{
+				DataType:    aws.String("String"),
+				StringValue: aws.String(v),
+			}
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), outcomeTimeout)
+	defer cancel()
+	_, err := p.svc.SendMessageWithContext(ctx, input)
+	return err
+}
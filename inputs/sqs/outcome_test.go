@@ -0,0 +1,108 @@
+package sqs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/gabrielperezs/goreactor/lib"
+	"github.com/stretchr/testify/assert"
+)
+
+func (f *fakeSQS) SendMessageWithContext(ctx aws.Context, in *sqs.SendMessageInput, opts ...request.Option) (*sqs.SendMessageOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sent = append(f.sent, in)
+	return &sqs.SendMessageOutput{MessageId: aws.String("id")}, nil
+}
+
+func newOutcomeListen(f *fakeSQS) *sqsListen {
+	return &sqsListen{
+		url:         "https://sqs.eu-west-1.amazonaws.com/1/queue",
+		svc:         f,
+		batcher:     newBatcher(f, "https://sqs.eu-west-1.amazonaws.com/1/queue", 10*time.Millisecond),
+		pendings:    make(map[string]int),
+		messError:   make(map[string]bool),
+		deadLetters: make(map[string]lib.Outcome),
+	}
+}
+
+func outcomeMsg(p *sqsListen, handle string) *Msg {
+	p.pendings[handle] = 1
+	return &Msg{
+		SQS:        p.svc,
+		M:          &sqs.DeleteMessageBatchRequestEntry{ReceiptHandle: aws.String(handle)},
+		Raw:        []byte(`{"raw":true}`),
+		B:          []byte(`{}`),
+		Attributes: map[string]string{"Type": "order"},
+		Hash:       "hash-" + handle,
+		listener:   p,
+	}
+}
+
+func TestDoneOutcome(t *testing.T) {
+	f := &fakeSQS{}
+	p := newOutcomeListen(f)
+
+	p.DoneOutcome(outcomeMsg(p, "ack"), lib.Outcome{Action: lib.ActionAck})
+	p.DoneOutcome(outcomeMsg(p, "drop"), lib.Outcome{Action: lib.ActionDrop})
+	p.DoneOutcome(outcomeMsg(p, "retry"), lib.Outcome{Action: lib.ActionRetry, Delay: 1500 * time.Millisecond})
+	p.DoneOutcome(outcomeMsg(p, "dlq"), lib.Outcome{Action: lib.ActionDeadLetter, DeadLetterQueue: "https://sqs.eu-west-1.amazonaws.com/1/dlq.fifo"})
+	p.batcher.Close()
+
+	// Only the acked and the dead-lettered messages are deleted
+	var deleted []string
+	for _, d := range f.deletes {
+		deleted = append(deleted, d...)
+	}
+	assert.ElementsMatch(t, []string{"ack", "dlq"}, deleted)
+	assert.Equal(t, [][]string{{"retry"}}, f.changes)
+	assert.Empty(t, p.pendings)
+
+	assert.Len(t, f.sent, 1)
+	sent := f.sent[0]
+	assert.Equal(t, "https://sqs.eu-west-1.amazonaws.com/1/dlq.fifo", *sent.QueueUrl)
+	assert.Equal(t, `{"raw":true}`, *sent.MessageBody)
+	assert.Equal(t, "order", *sent.MessageAttributes["Type"].StringValue)
+	assert.Equal(t, "hash-dlq", *sent.MessageGroupId)
+	assert.Equal(t, "hash-dlq", *sent.MessageDeduplicationId)
+}
+
+func TestDoneOutcomeRetryWithoutDelay(t *testing.T) {
+	f := &fakeSQS{}
+	p := newOutcomeListen(f)
+
+	// The message is only released, it's visible again after the timeout
+	p.DoneOutcome(outcomeMsg(p, "retry"), lib.Outcome{Action: lib.ActionRetry})
+	p.batcher.Close()
+	assert.Empty(t, f.changes)
+	assert.Empty(t, f.deletes)
+	assert.Empty(t, p.pendings)
+}
+
+func TestDoneOutcomeDeadLetterOnce(t *testing.T) {
+	f := &fakeSQS{}
+	p := newOutcomeListen(f)
+	dlq := lib.Outcome{Action: lib.ActionDeadLetter, DeadLetterQueue: "https://sqs.eu-west-1.amazonaws.com/1/dlq"}
+
+	// Three clones of the message, from several reactors or records
+	m := outcomeMsg(p, "split")
+	p.pendings["split"] = 3
+	p.DoneOutcome(m, dlq)
+	p.Done(m, false)
+	assert.Empty(t, f.sent, "sent before all the clones finished")
+	p.DoneOutcome(m, dlq)
+	p.batcher.Close()
+
+	assert.Len(t, f.sent, 1)
+	assert.Equal(t, `{"raw":true}`, *f.sent[0].MessageBody)
+	var deleted []string
+	for _, d := range f.deletes {
+		deleted = append(deleted, d...)
+	}
+	assert.Equal(t, []string{"split"}, deleted)
+	assert.Empty(t, p.pendings)
+	assert.Empty(t, p.deadLetters)
+}
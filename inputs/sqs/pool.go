@@ -12,10 +12,9 @@ import (
 	"sync/atomic"
 	"time"
 
-	"github.com/Jeffail/gabs"
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
 	"github.com/gabrielperezs/goreactor/lib"
 	"github.com/gabrielperezs/goreactor/reactor"
 	"github.com/gallir/dynsemaphore"
@@ -23,93 +22,166 @@ import (
 
 var MessageSystemAttributeNameSentTimestamp = sqs.MessageSystemAttributeNameSentTimestamp
 
-var connPool sync.Map
+// pollInterval is the wait before checking again if a queue with priority
+// can be polled
+const pollInterval = 100 * time.Millisecond
+
+var (
+	connPool sync.Map
+	poolMu   sync.Mutex // Serializes the creation and the release of the listeners
+)
 
 type sqsListen struct {
 	sync.Mutex
-	url                 string
-	region              string
-	profile             string
-	maxNumberOfMessages int64
-	noBlocking          bool // If true, the input will not block waiting for a reactor to finish
+	listenConfig
+	url     string
+	batcher *batcher
+	breaker *breaker
 
-	svc *sqs.SQS
+	svc sqsiface.SQSAPI
 
 	exiting  uint32
 	exited   bool
 	exitedMu sync.Mutex
 	done     chan bool
 
+	refs              int // Queues of the reactors using the listener, protected by poolMu
 	broadcastCh       sync.Map
 	pendings          map[string]int
 	messError         map[string]bool
+	deadLetters       map[string]lib.Outcome     // The first dead-letter outcome of the pending messages
 	maxQueuedMessages *dynsemaphore.DynSemaphore // Max of goroutines wating to send the message
 }
 
-func newSQSListen(r *reactor.Reactor, c map[string]any) (*sqsListen, error) {
+// subscription is a reactor that receives the messages of the listener
+type subscription struct {
+	concurrent int
+	priority   int
+	slots      *prioritySlots // Shared by all the queues of the reactor, nil if it has only one
+	working    bool           // The last poll of the queue returned messages, protected by the listener
+}
 
-	p := &sqsListen{
-		done: make(chan bool),
-	}
-
-	for k, v := range c {
-		switch strings.ToLower(k) {
-		case "url":
-			p.url = v.(string)
-		case "region":
-			p.region = v.(string)
-		case "profile":
-			p.profile = v.(string)
-		case "maxnumberofmessages":
-			p.maxNumberOfMessages, _ = v.(int64)
-		case "noblocking":
-			p.noBlocking, _ = v.(bool)
+func (s *subscription) acquire() {
+	if s.slots != nil {
+		s.slots.Acquire(s.priority)
+	}
+}
+
+func (s *subscription) release() {
+	if s.slots != nil {
+		s.slots.Release()
+	}
+}
+
+func (s *subscription) canPoll() bool {
+	return s.slots == nil || s.slots.CanPoll(s.priority)
+}
+
+func (s *subscription) polled(received bool) {
+	if s.slots != nil {
+		s.slots.Polled(s.priority, s.working, received)
+		s.working = received
+	}
+}
+
+// acquireListen returns the listener of the URL, creating it if there is none,
+// and increases its references. The settings of the reactor must be the same
+// of the listener.
+func acquireListen(url string, c map[string]any) (*sqsListen, error) {
+	cfg, err := newListenConfig(c)
+	if err != nil {
+		return nil, err
+	}
+
+	poolMu.Lock()
+	defer poolMu.Unlock()
+
+	if nl, ok := connPool.Load(url); ok {
+		l := nl.(*sqsListen)
+		if atomic.LoadUint32(&l.exiting) == 0 {
+			if conflicts := l.listenConfig.conflicts(cfg); len(conflicts) > 0 {
+				return nil, fmt.Errorf("SQS ERROR: %s is used by other reactors with different %s",
+					url, strings.Join(conflicts, ", "))
+			}
+			l.refs++
+			return l, nil
 		}
 	}
 
-	if p.maxNumberOfMessages == 0 {
-		p.maxNumberOfMessages = defaultMaxNumberOfMessages
+	l, err := newSQSListen(url, cfg)
+	if err != nil {
+		return nil, err
 	}
+	l.refs = 1
+	connPool.Store(url, l)
+	return l, nil
+}
 
-	if p.url == "" {
-		return nil, fmt.Errorf("SQS ERROR: URL not found or invalid")
+// releaseListen decreases the references of the listener, the last one
+// exits the listener and removes it from the pool
+func releaseListen(l *sqsListen) {
+	poolMu.Lock()
+	l.refs--
+	last := l.refs <= 0
+	if last {
+		connPool.CompareAndDelete(l.url, l)
+	}
+	poolMu.Unlock()
+
+	if last {
+		l.Exit()
+	}
+}
+
+func newSQSListen(url string, cfg listenConfig) (*sqsListen, error) {
+	p := &sqsListen{
+		listenConfig: cfg,
+		url:          url,
+		done:         make(chan bool),
 	}
 
-	if p.region == "" {
-		return nil, fmt.Errorf("SQS ERROR: Region not found or invalid")
+	if p.url == "" {
+		return nil, fmt.Errorf("SQS ERROR: URL not found or invalid")
 	}
 
 	log.Printf("SQS NEW %s", p.url)
 
-	sess, err := session.NewSessionWithOptions(session.Options{
-		Profile:           p.profile,
-		SharedConfigState: session.SharedConfigEnable,
-	})
+	var err error
+	p.svc, err = newSQSClient(p.Options)
 	if err != nil {
 		return nil, err
 	}
-	p.broadcastCh.Store(r, r.Concurrent)
 	p.pendings = make(map[string]int)
 	p.messError = make(map[string]bool)
+	p.deadLetters = make(map[string]lib.Outcome)
 
-	p.svc = sqs.New(sess, &aws.Config{Region: aws.String(p.region)})
+	p.breaker = newBreaker(p.url, p.errorBackoffMin, p.errorBackoffMax, p.circuitBreakerThreshold)
+	p.batcher = newBatcher(p.svc, p.url, p.batchInterval)
 	p.maxQueuedMessages = dynsemaphore.New(0)
-	p.updateConcurrency()
 
 	go p.listen()
 
 	return p, nil
 }
 
-func (p *sqsListen) AddOrUpdate(r *reactor.Reactor) {
-	p.broadcastCh.Store(r, r.Concurrent)
+// AddOrUpdate subscribes the reactor to the messages of the listener
+func (p *sqsListen) AddOrUpdate(r *reactor.Reactor, sub *subscription) {
+	p.broadcastCh.Store(r, sub)
 	p.updateConcurrency()
 }
 
+// Remove unsubscribes the reactor, the messages already delivered to it are
+// still tracked as pending until they are done
+func (p *sqsListen) Remove(r *reactor.Reactor) {
+	if _, ok := p.broadcastCh.LoadAndDelete(r); ok {
+		p.updateConcurrency()
+	}
+}
+
 func (p *sqsListen) updateConcurrency() {
 	total := 0
 	p.broadcastCh.Range(func(k, v any) bool {
-		total += v.(int)
+		total += v.(*subscription).concurrent
 		return true
 	})
 	maxPendings := total
@@ -142,20 +214,33 @@ func (p *sqsListen) listen() {
 			return
 		}
 
+		if !p.canPoll() {
+			time.Sleep(pollInterval)
+			continue
+		}
+
 		params := &sqs.ReceiveMessageInput{
-			QueueUrl:            aws.String(p.url),
-			MaxNumberOfMessages: aws.Int64(p.maxNumberOfMessages),
-			WaitTimeSeconds:     aws.Int64(waitTimeSeconds),
-			AttributeNames:      []*string{&MessageSystemAttributeNameSentTimestamp},
+			QueueUrl:              aws.String(p.url),
+			MaxNumberOfMessages:   aws.Int64(p.maxNumberOfMessages),
+			WaitTimeSeconds:       aws.Int64(p.waitTimeSeconds),
+			AttributeNames:        aws.StringSlice(p.attributeNames),
+			MessageAttributeNames: aws.StringSlice(p.messageAttributeNames),
+		}
+		if p.visibilityTimeout > 0 {
+			params.VisibilityTimeout = aws.Int64(p.visibilityTimeout)
 		}
 
+		p.breaker.retry()
 		resp, err := p.svc.ReceiveMessage(params)
 
 		if err != nil {
-			log.Printf("ERROR: AWS session on %s - %s", p.url, err)
-			time.Sleep(15 * time.Second)
+			d := p.breaker.failure()
+			log.Printf("ERROR: AWS session on %s - %s, retrying in %s", p.url, err, d.Round(time.Millisecond))
+			p.sleep(d)
 			continue
 		}
+		p.breaker.success()
+		p.polled(len(resp.Messages) > 0)
 
 		for _, msg := range resp.Messages {
 			p.deliver(msg)
@@ -163,6 +248,14 @@ func (p *sqsListen) listen() {
 	}
 }
 
+// sleep waits d or until the listener is stopping
+func (p *sqsListen) sleep(d time.Duration) {
+	deadline := time.Now().Add(d)
+	for time.Now().Before(deadline) && atomic.LoadUint32(&p.exiting) == 0 {
+		time.Sleep(min(time.Second, time.Until(deadline)))
+	}
+}
+
 func (p *sqsListen) deliver(msg *sqs.Message) {
 	timestamp, ok := msg.Attributes[sqs.MessageSystemAttributeNameSentTimestamp]
 	var sentTimestamp int64
@@ -173,30 +266,48 @@ func (p *sqsListen) deliver(msg *sqs.Message) {
 	m := &Msg{
 		SQS: p.svc,
 		B:   []byte(*msg.Body),
+		Raw: []byte(*msg.Body),
 		M: &sqs.DeleteMessageBatchRequestEntry{
 			Id:            msg.MessageId,
 			ReceiptHandle: msg.ReceiptHandle,
 		},
 		URL:           aws.String(p.url),
+		listener:      p,
 		SentTimestamp: sentTimestamp,
 		Hash:          *msg.MessageId,
 		doneCh:        make(chan struct{}),
 	}
 
-	jsonParsed, err := gabs.ParseJSON(m.B)
-	if err == nil && jsonParsed.Exists("Message") {
-		s := strings.ReplaceAll(jsonParsed.S("Message").String(), "\\\"", "\"")
-		s = strings.TrimPrefix(s, "\"")
-		s = strings.TrimSuffix(s, "\"")
-		m.B = []byte(s)
+	if len(msg.MessageAttributes) > 0 {
+		m.Attributes = make(map[string]string, len(msg.MessageAttributes))
+		for k, v := range msg.MessageAttributes {
+			if v.StringValue != nil {
+				m.Attributes[k] = *v.StringValue
+			}
+		}
+	}
+
+	m.B, m.Envelope = unwrapEnvelope(p.envelope, m.B)
+
+	// Without reactors the message can't be validated, it's left in the queue
+	if !p.hasSubscribers() {
+		return
 	}
 
 	// Flag to delete the message if don't match with at least one reactor condition
 	atLeastOneValid := false
-	if p.noBlocking {
-		atLeastOneValid = p.deliverNoBlocking(m)
+	if records := p.splitRecords(m); records != nil {
+		// Keep the message pending until all the records were delivered,
+		// the records share the receipt handle and so the pending count
+		p.addPending(m)
+		for _, rm := range records {
+			if p.deliverMsg(rm) {
+				atLeastOneValid = true
+			}
+		}
+		p.Done(m, atLeastOneValid)
 	} else {
-		atLeastOneValid = p.deliverBlocking(m)
+		atLeastOneValid = p.deliverMsg(m)
 	}
 
 	// We delete this message if is invalid for all the reactors
@@ -206,6 +317,24 @@ func (p *sqsListen) deliver(msg *sqs.Message) {
 	}
 }
 
+// splitRecords returns the messages of each record if the message must be
+// executed once per record, nil otherwise
+func (p *sqsListen) splitRecords(m *Msg) []*Msg {
+	if !p.s3Records {
+		return nil
+	}
+	return s3Records(m)
+}
+
+// deliverMsg send the message to the reactors, it returns false if the
+// message is not valid for any of them
+func (p *sqsListen) deliverMsg(m *Msg) bool {
+	if p.noBlocking {
+		return p.deliverNoBlocking(m)
+	}
+	return p.deliverBlocking(m)
+}
+
 // deliverBlocking send the message to the reactors in sequence
 func (p *sqsListen) deliverBlocking(m *Msg) (atLeastOneValid bool) {
 	p.broadcastCh.Range(func(k, v any) bool {
@@ -214,8 +343,10 @@ func (p *sqsListen) deliverBlocking(m *Msg) (atLeastOneValid bool) {
 		}
 		atLeastOneValid = true
 		p.addPending(m)
-		k.(*reactor.Reactor).Ch <- m
-		m.Wait()
+		sub := v.(*subscription)
+		sub.acquire()
+		p.send(k.(*reactor.Reactor), m.clone())
+		sub.release()
 		return true
 	})
 	return
@@ -232,17 +363,57 @@ func (p *sqsListen) deliverNoBlocking(m *Msg) (atLeastOneValid bool) {
 		p.addPending(m)
 		p.maxQueuedMessages.Access() // Check the limit of goroutines
 		go func(m *Msg) {
+			sub := v.(*subscription)
+			sub.acquire()
 			defer func() {
+				sub.release()
 				p.maxQueuedMessages.Release()
-				if r := recover(); r != nil {
-					return // Ignore "closed channel" error when the program finishes
-				}
 			}()
-			k.(*reactor.Reactor).Ch <- m
-			m.Wait()
-		}(m)
+			p.send(k.(*reactor.Reactor), m)
+		}(m.clone())
+		return true
+	})
+	return
+}
+
+// send delivers the message to the reactor and waits until it's done. If the
+// reactor was closed meanwhile the message is kept in the queue.
+func (p *sqsListen) send(r *reactor.Reactor, m *Msg) {
+	defer func() {
+		if recover() != nil { // The channel of the reactor is closed
+			p.Done(m, false)
+		}
+	}()
+	r.Ch <- m
+	m.Wait()
+}
+
+// canPoll returns true if at least one reactor can take the messages of this
+// queue: a reactor with only one queue, or with several queues and a free
+// slot and no queue with higher priority with messages. The other reactors
+// wait for a slot for the messages received.
+func (p *sqsListen) canPoll() (ok bool) {
+	p.broadcastCh.Range(func(k, v any) bool {
+		ok = v.(*subscription).canPoll()
+		return !ok
+	})
+	return ok
+}
+
+// polled records in the reactors with several queues if the queue has messages
+func (p *sqsListen) polled(received bool) {
+	p.broadcastCh.Range(func(k, v any) bool {
+		v.(*subscription).polled(received)
 		return true
 	})
+}
+
+// hasSubscribers returns true if at least one reactor receives the messages
+func (p *sqsListen) hasSubscribers() (ok bool) {
+	p.broadcastCh.Range(func(k, v any) bool {
+		ok = true
+		return false
+	})
 	return
 }
 
@@ -264,26 +435,22 @@ func (p *sqsListen) Exit() error {
 
 	p.Stop()
 	p.exited = <-p.done
+	p.batcher.Close() // Send the last deletes
 	return nil
 }
 
-func (p *sqsListen) delete(v lib.Msg) (err error) {
+// delete adds the message to the next delete batch, the errors are logged
+func (p *sqsListen) delete(v lib.Msg) {
 	msg, ok := v.(*Msg)
 	if !ok {
 		log.Printf("ERROR SQS Delete: invalid message %+v", v)
 		return
 	}
 
-	if msg.SQS == nil || msg == nil {
+	if msg == nil || msg.SQS == nil {
 		return
 	}
-	if _, err = msg.SQS.DeleteMessage(&sqs.DeleteMessageInput{
-		QueueUrl:      msg.URL,
-		ReceiptHandle: msg.M.ReceiptHandle,
-	}); err != nil {
-		log.Printf("ERROR: %s - %s", *msg.URL, err)
-	}
-	return
+	p.batcher.Delete(msg.M.ReceiptHandle)
 }
 
 func (p *sqsListen) KeepAlive(ctx context.Context, t time.Duration, v lib.Msg) (err error) {
@@ -293,7 +460,7 @@ func (p *sqsListen) KeepAlive(ctx context.Context, t time.Duration, v lib.Msg) (
 		return
 	}
 
-	if msg.SQS == nil || msg == nil {
+	if msg == nil || msg.SQS == nil {
 		return
 	}
 
@@ -301,11 +468,7 @@ func (p *sqsListen) KeepAlive(ctx context.Context, t time.Duration, v lib.Msg) (
 	t = t + time.Duration(float64(t)*0.1)
 	sec := int64(math.Ceil(t.Seconds()))
 
-	if _, err = msg.SQS.ChangeMessageVisibilityWithContext(ctx, &sqs.ChangeMessageVisibilityInput{
-		QueueUrl:          msg.URL,
-		ReceiptHandle:     msg.M.ReceiptHandle,
-		VisibilityTimeout: aws.Int64(sec),
-	}); err != nil {
+	if err = p.batcher.ChangeVisibility(ctx, msg.M.ReceiptHandle, sec); err != nil {
 		log.Printf("ERROR: %s - %s", *msg.URL, err)
 	}
 	return
@@ -330,6 +493,13 @@ func (p *sqsListen) addPending(m lib.Msg) {
 
 // Done removes the message from the pending queue.
 func (p *sqsListen) Done(m lib.Msg, statusOk bool) {
+	p.finish(m, statusOk, nil)
+}
+
+// finish removes the message from the pending queue. When the last reactor
+// finishes the message is deleted, or sent once to the dead-letter queue if
+// any of the reactors asked for it
+func (p *sqsListen) finish(m lib.Msg, statusOk bool, deadLetter *lib.Outcome) {
 	msg, ok := m.(*Msg)
 	if !ok {
 		return
@@ -337,11 +507,10 @@ func (p *sqsListen) Done(m lib.Msg, statusOk bool) {
 	id := *msg.M.ReceiptHandle
 
 	p.Lock()
-	defer p.Unlock()
-
 	// If it's not in pending, ignore it
 	v, ok := p.pendings[id]
 	if !ok {
+		p.Unlock()
 		return
 	}
 	v -= 1
@@ -349,14 +518,33 @@ func (p *sqsListen) Done(m lib.Msg, statusOk bool) {
 	if !statusOk {
 		p.messError[id] = true
 	}
+	if _, found := p.deadLetters[id]; deadLetter != nil && !found {
+		p.deadLetters[id] = *deadLetter
+	}
 
 	// Check if it's the last
-	if v <= 0 {
-		delete(p.pendings, id)
-		_, hadError := p.messError[id]
-		if !hadError {
-			// Delete the message if there's no more pending reactors
-			go p.delete(m) // Execute delete message outside the Lock
+	if v > 0 {
+		p.Unlock()
+		return
+	}
+	delete(p.pendings, id)
+	_, hadError := p.messError[id]
+	delete(p.messError, id)
+	o, isDeadLetter := p.deadLetters[id]
+	delete(p.deadLetters, id)
+	p.Unlock()
+
+	if isDeadLetter {
+		if err := p.deadLetter(msg, o.DeadLetterQueue); err != nil {
+			log.Printf("ERROR: %s - dead-letter of %s to %s: %s", p.url, msg.Hash, o.DeadLetterQueue, err)
+			return
 		}
+		log.Printf("SQS message %s from %s moved to %s, exit code %d", msg.Hash, p.url, o.DeadLetterQueue, o.ExitCode)
+		p.delete(m) // The dead-letter queue has the copy, even if other reactors failed
+		return
+	}
+	if !hadError {
+		// Delete the message if there's no more pending reactors
+		p.delete(m) // It's only queued in the next delete batch
 	}
 }
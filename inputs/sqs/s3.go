@@ -0,0 +1,62 @@
+package sqs
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// s3Event is the notification sent by S3, one message can contain several records
+type s3Event struct {
+	Records []json.RawMessage
+}
+
+type s3Record struct {
+	EventName string `json:"eventName"`
+	S3        *struct {
+		Bucket struct {
+			Name string `json:"name"`
+		} `json:"bucket"`
+		Object struct {
+			Key  string `json:"key"`
+			Size int64  `json:"size"`
+		} `json:"object"`
+	} `json:"s3"`
+}
+
+// s3Records splits a S3 event notification in one message per record. It
+// returns nil if the message is not a S3 event notification.
+func s3Records(m *Msg) []*Msg {
+	e := &s3Event{}
+	if err := json.Unmarshal(m.B, e); err != nil || len(e.Records) == 0 {
+		return nil
+	}
+
+	msgs := make([]*Msg, 0, len(e.Records))
+	for i, raw := range e.Records {
+		r := &s3Record{}
+		if err := json.Unmarshal(raw, r); err != nil || r.S3 == nil {
+			return nil
+		}
+
+		// Keys are URL encoded in the notifications, spaces as +
+		key, err := url.QueryUnescape(r.S3.Object.Key)
+		if err != nil {
+			key = r.S3.Object.Key
+		}
+
+		rm := *m
+		rm.B = raw
+		rm.Hash = fmt.Sprintf("%s-%d", m.Hash, i)
+		rm.Variables = map[string]string{
+			"S3Bucket":    r.S3.Bucket.Name,
+			"S3Key":       key,
+			"S3Size":      strconv.FormatInt(r.S3.Object.Size, 10),
+			"S3EventName": r.EventName,
+		}
+		rm.doneCh = make(chan struct{})
+		msgs = append(msgs, &rm)
+	}
+	return msgs
+}
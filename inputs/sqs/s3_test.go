@@ -0,0 +1,35 @@
+package sqs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestS3Records(t *testing.T) {
+	m := &Msg{
+		Hash: "id",
+		B: []byte(`{"Records":[
+			{"eventName":"ObjectCreated:Put","s3":{"bucket":{"name":"bucket-a"},"object":{"key":"dir/my+file%281%29.txt","size":1024}}},
+			{"eventName":"ObjectRemoved:Delete","s3":{"bucket":{"name":"bucket-b"},"object":{"key":"other.txt"}}}
+		]}`),
+	}
+
+	records := s3Records(m)
+	assert.Equal(t, 2, len(records))
+
+	assert.Equal(t, "id-0", records[0].GetHash())
+	assert.Equal(t, map[string]string{
+		"S3Bucket":    "bucket-a",
+		"S3Key":       "dir/my file(1).txt",
+		"S3Size":      "1024",
+		"S3EventName": "ObjectCreated:Put",
+	}, records[0].Variables)
+
+	v, _ := records[1].Variable("S3Bucket")
+	assert.Equal(t, "bucket-b", v)
+	assert.Contains(t, string(records[1].Body()), `"other.txt"`)
+
+	assert.Nil(t, s3Records(&Msg{B: []byte(`{"id":1}`)}))
+	assert.Nil(t, s3Records(&Msg{B: []byte(`{"Records":[{"eventSource":"aws:sns"}]}`)}))
+}
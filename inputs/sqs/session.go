@@ -0,0 +1,18 @@
+package sqs
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/gabrielperezs/goreactor/lib/awssession"
+)
+
+// newSQSClient creates the SQS client with the credentials and endpoint defined
+// in the options
+func newSQSClient(o awssession.Options) (*sqs.SQS, error) {
+	sess, cfg, err := awssession.New(o)
+	if err != nil {
+		return nil, fmt.Errorf("SQS ERROR: %w", err)
+	}
+	return sqs.New(sess, cfg), nil
+}
@@ -0,0 +1,99 @@
+package sqs
+
+import (
+	"sync"
+)
+
+// prioritySlots limits the messages in process of a reactor that reads from
+// several queues. When a slot is released it's given to the message of the
+// queue with the highest priority that is waiting, in arrival order for the
+// same priority.
+//
+// The queues are only polled when there is a free slot and no queue with a
+// higher priority has messages, so the messages of the queues with lower
+// priority don't wait in flight for a slot.
+type prioritySlots struct {
+	mu      sync.Mutex
+	free    int
+	waiters []*slotWaiter
+	working map[int]int // Queues of each priority whose last poll returned messages
+}
+
+type slotWaiter struct {
+	priority int
+	ch       chan struct{}
+}
+
+func newPrioritySlots(n int) *prioritySlots {
+	return &prioritySlots{free: n, working: make(map[int]int)}
+}
+
+// CanPoll returns true if the queue of this priority can receive messages:
+// there is a free slot and no queue with a higher priority has messages
+func (s *prioritySlots) CanPoll(priority int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.free <= 0 || len(s.waiters) > 0 {
+		return false
+	}
+	for p, n := range s.working {
+		if p > priority && n > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Polled records if the last poll of a queue of this priority returned
+// messages, working is the previous state of the queue
+func (s *prioritySlots) Polled(priority int, working, received bool) {
+	if working == received {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if received {
+		s.working[priority]++
+	} else {
+		s.working[priority]--
+	}
+}
+
+// Acquire blocks until there is a free slot for this priority
+func (s *prioritySlots) Acquire(priority int) {
+	s.mu.Lock()
+	if s.free > 0 && len(s.waiters) == 0 {
+		s.free--
+		s.mu.Unlock()
+		return
+	}
+
+	w := &slotWaiter{priority: priority, ch: make(chan struct{})}
+	i := len(s.waiters)
+	for i > 0 && s.waiters[i-1].priority < priority {
+		i--
+	}
+	s.waiters = append(s.waiters, nil)
+	copy(s.waiters[i+1:], s.waiters[i:])
+	s.waiters[i] = w
+	s.mu.Unlock()
+
+	<-w.ch
+}
+
+// Release gives the slot to the first waiter or leaves it free
+func (s *prioritySlots) Release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.waiters) > 0 {
+		w := s.waiters[0]
+		s.waiters = s.waiters[1:]
+		close(w.ch)
+		return
+	}
+	s.free++
+}
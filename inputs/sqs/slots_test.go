@@ -0,0 +1,71 @@
+package sqs
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gabrielperezs/goreactor/reactor"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrioritySlots(t *testing.T) {
+	s := newPrioritySlots(1)
+	s.Acquire(0) // Busy
+
+	var mu sync.Mutex
+	var order []int
+	var wg sync.WaitGroup
+	for _, priority := range []int{1, 10, 5, 10} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.Acquire(priority)
+			mu.Lock()
+			order = append(order, priority)
+			mu.Unlock()
+			s.Release()
+		}()
+		time.Sleep(10 * time.Millisecond) // Keep the arrival order
+	}
+
+	s.Release()
+	wg.Wait()
+
+	assert.Equal(t, []int{10, 10, 5, 1}, order)
+	assert.Equal(t, 1, s.free)
+}
+
+func TestPrioritySlotsCanPoll(t *testing.T) {
+	s := newPrioritySlots(1)
+	assert.True(t, s.CanPoll(0))
+	assert.True(t, s.CanPoll(10))
+
+	// The queue with higher priority has messages
+	s.Polled(10, false, true)
+	assert.False(t, s.CanPoll(0))
+	assert.True(t, s.CanPoll(10))
+
+	// No free slot
+	s.Acquire(10)
+	assert.False(t, s.CanPoll(10))
+	s.Release()
+
+	// The queue with higher priority is empty
+	s.Polled(10, true, false)
+	assert.True(t, s.CanPoll(0))
+	s.Polled(0, false, false)
+	assert.Equal(t, 0, s.working[0])
+}
+
+func TestSharedListenCanPoll(t *testing.T) {
+	p := &sqsListen{}
+	full := newPrioritySlots(1)
+	full.Acquire(0)
+	p.broadcastCh.Store(&reactor.Reactor{}, &subscription{slots: full})
+	assert.False(t, p.canPoll(), "the only reactor has no free slot")
+
+	// Another reactor of the same URL can take the messages
+	p.broadcastCh.Store(&reactor.Reactor{}, &subscription{})
+	assert.True(t, p.canPoll())
+}
@@ -3,6 +3,7 @@ package sqs
 import (
 	"context"
 	"fmt"
+	"log"
 	"strings"
 	"time"
 
@@ -12,13 +13,20 @@ import (
 
 const (
 	defaultMaxNumberOfMessages = 10 // Default limit of messages can be read from SQS
-	waitTimeSeconds            = 15 // Seconds to keep open the connection to SQS
+	defaultWaitTimeSeconds     = 15 // Seconds to keep open the connection to SQS
 )
 
+// queue is one of the SQS queues of the reactor
+type queue struct {
+	url      string
+	priority int
+	l        *sqsListen
+}
+
 // SQSPlugin struct for SQS Input plugin
 type SQSPlugin struct {
 	r       *reactor.Reactor
-	l       *sqsListen
+	queues  []*queue
 	URL     string
 	Region  string
 	Profile string
@@ -37,6 +45,14 @@ func NewOrGet(r *reactor.Reactor, c map[string]any) (*SQSPlugin, error) {
 		switch strings.ToLower(k) {
 		case "url":
 			p.URL = v.(string)
+		case "queues":
+			for _, n := range v.([]any) {
+				q, err := newQueue(n)
+				if err != nil {
+					return nil, err
+				}
+				p.queues = append(p.queues, q)
+			}
 		case "region":
 			p.Region = v.(string)
 		case "profile":
@@ -44,50 +60,114 @@ func NewOrGet(r *reactor.Reactor, c map[string]any) (*SQSPlugin, error) {
 		}
 	}
 
-	if p.URL == "" {
+	if p.URL != "" {
+		p.queues = append(p.queues, &queue{url: p.URL})
+	}
+
+	if len(p.queues) == 0 {
 		return nil, fmt.Errorf("SQS ERROR: URL not found or invalid")
 	}
+	p.URL = p.queues[0].url
 
 	if p.Region == "" {
 		return nil, fmt.Errorf("SQS ERROR: Region not found or invalid")
 	}
 
-	if nl, ok := connPool.Load(p.URL); !ok {
+	// With several queues the concurrency of the reactor is shared,
+	// the free slots are given first to the queues with higher priority
+	var slots *prioritySlots
+	if len(p.queues) > 1 {
+		slots = newPrioritySlots(r.Concurrent)
+	}
+
+	for i, q := range p.queues {
 		var err error
-		p.l, err = newSQSListen(r, c)
+		q.l, err = acquireListen(q.url, c)
 		if err != nil {
+			for _, prev := range p.queues[:i] {
+				prev.l.Remove(r)
+				releaseListen(prev.l)
+			}
 			return nil, err
 		}
-		connPool.Store(p.URL, p.l)
-	} else {
-		p.l = nl.(*sqsListen)
-	}
 
-	p.l.AddOrUpdate(r)
+		q.l.AddOrUpdate(r, &subscription{
+			concurrent: r.Concurrent,
+			priority:   q.priority,
+			slots:      slots,
+		})
+		if slots != nil {
+			log.Printf("SQS reactor %d queue %s priority %d", r.GetID(), q.url, q.priority)
+		}
+	}
 
 	return p, nil
 }
 
+func newQueue(cfg any) (*queue, error) {
+	c, ok := cfg.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("SQS ERROR: invalid queue %v", cfg)
+	}
+
+	q := &queue{}
+	for k, v := range c {
+		switch strings.ToLower(k) {
+		case "url":
+			q.url, _ = v.(string)
+		case "priority":
+			n, _ := v.(int64)
+			q.priority = int(n)
+		}
+	}
+
+	if q.url == "" {
+		return nil, fmt.Errorf("SQS ERROR: queue without URL")
+	}
+	return q, nil
+}
+
 // Put is not needed in SQS
 func (p *SQSPlugin) Put(v lib.Msg) error {
 	return nil
 }
 
-// Exit stops the pooling from SQS
+// Exit releases the listeners of the queues, the pooling from SQS is
+// stopped when the last reactor of the queue exits
 func (p *SQSPlugin) Exit() {
-	p.l.Exit()
-	connPool.Delete(p.URL)
+	for _, q := range p.queues {
+		q.l.Remove(p.r)
+		releaseListen(q.l)
+	}
 }
 
-// Stops listening
+// Stop the delivery of messages to the reactor, the listeners without
+// other reactors stop listening
 func (p *SQSPlugin) Stop() {
-	p.l.Stop()
+	for _, q := range p.queues {
+		q.l.Remove(p.r)
+		if !q.l.hasSubscribers() {
+			q.l.Stop()
+		}
+	}
 }
 
 func (p *SQSPlugin) Done(v lib.Msg, status bool) {
-	p.l.Done(v, status)
+	if msg, ok := v.(*Msg); ok && msg.listener != nil {
+		msg.listener.Done(v, status)
+	}
+}
+
+// DoneOutcome executes the action of the outcome of the message
+func (p *SQSPlugin) DoneOutcome(v lib.Msg, o lib.Outcome) {
+	if msg, ok := v.(*Msg); ok && msg.listener != nil {
+		msg.listener.DoneOutcome(v, o)
+	}
 }
 
 func (p *SQSPlugin) KeepAlive(ctx context.Context, t time.Duration, v lib.Msg) (err error) {
-	return p.l.KeepAlive(ctx, t, v)
+	if msg, ok := v.(*Msg); ok && msg.listener != nil {
+		return msg.listener.KeepAlive(ctx, t, v)
+	}
+	return nil
 }
@@ -0,0 +1,168 @@
+//go:build integration
+// +build integration
+
+package main
+
+// End to end tests against a local SQS compatible service, like ElasticMQ:
+//
+//	docker run --rm -p 9324:9324 softwaremill/elasticmq-native
+//	GOREACTOR_SQS_ENDPOINT=http://localhost:9324 go test -tags integration .
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/gabrielperezs/goreactor/inputs"
+	"github.com/gabrielperezs/goreactor/outputs"
+	"github.com/gabrielperezs/goreactor/reactor"
+	"github.com/stretchr/testify/assert"
+)
+
+const integrationRegion = "elasticmq"
+
+func integrationEndpoint(t *testing.T) string {
+	endpoint := os.Getenv("GOREACTOR_SQS_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("GOREACTOR_SQS_ENDPOINT is not defined")
+	}
+	return endpoint
+}
+
+func integrationQueue(t *testing.T, endpoint string) (*sqs.SQS, string) {
+	sess, err := session.NewSession(&aws.Config{
+		Region:      aws.String(integrationRegion),
+		Endpoint:    aws.String(endpoint),
+		Credentials: credentials.NewStaticCredentials("x", "x", ""),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := sqs.New(sess)
+
+	out, err := svc.CreateQueue(&sqs.CreateQueueInput{
+		QueueName: aws.String(fmt.Sprintf("goreactor-%d", time.Now().UnixNano())),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		svc.DeleteQueue(&sqs.DeleteQueueInput{QueueUrl: out.QueueUrl})
+	})
+	return svc, *out.QueueUrl
+}
+
+func integrationReactor(t *testing.T, cfg map[string]any) *reactor.Reactor {
+	r := reactor.NewReactor(cfg)
+
+	var err error
+	if r.I, err = inputs.Get(r, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if r.O, err = outputs.Get(r, cfg); err != nil {
+		t.Fatal(err)
+	}
+	r.Start()
+	t.Cleanup(func() {
+		r.Stop()
+		r.Exit()
+	})
+	return r
+}
+
+func pendingMessages(t *testing.T, svc *sqs.SQS, url string) int {
+	out, err := svc.GetQueueAttributes(&sqs.GetQueueAttributesInput{
+		QueueUrl: aws.String(url),
+		AttributeNames: []*string{
+			aws.String(sqs.QueueAttributeNameApproximateNumberOfMessages),
+			aws.String(sqs.QueueAttributeNameApproximateNumberOfMessagesNotVisible),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for _, v := range out.Attributes {
+		var i int
+		fmt.Sscan(*v, &i)
+		n += i
+	}
+	return n
+}
+
+func TestIntegrationRunAndDelete(t *testing.T) {
+	endpoint := integrationEndpoint(t)
+	svc, url := integrationQueue(t, endpoint)
+	out := filepath.Join(t.TempDir(), "out")
+
+	integrationReactor(t, map[string]any{
+		"concurrent": int64(2),
+		"input":      "sqs",
+		"url":        url,
+		"region":     integrationRegion,
+		"endpoint":   endpoint,
+		"accessKey":  "x",
+		"secretKey":  "x",
+		"output":     "cmd",
+		"cond":       []any{map[string]any{"$.Event": "^run$"}},
+		"cmd":        "/bin/sh",
+		"args":       []any{"-c", `echo "$1" >> ` + out, "sh", "$.id"},
+	})
+
+	for _, body := range []string{`{"Event":"run","id":"one"}`, `{"Event":"skip","id":"two"}`, `{"Event":"run","id":"three"}`} {
+		if _, err := svc.SendMessage(&sqs.SendMessageInput{QueueUrl: aws.String(url), MessageBody: aws.String(body)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	assert.Eventually(t, func() bool {
+		b, _ := os.ReadFile(out)
+		lines := strings.Fields(string(b))
+		return len(lines) == 2
+	}, 30*time.Second, 100*time.Millisecond)
+
+	b, _ := os.ReadFile(out)
+	assert.ElementsMatch(t, []string{"one", "three"}, strings.Fields(string(b)))
+
+	// Executed and invalid messages are deleted
+	assert.Eventually(t, func() bool {
+		return pendingMessages(t, svc, url) == 0
+	}, 30*time.Second, 500*time.Millisecond)
+}
+
+func TestIntegrationFailedCommandIsNotDeleted(t *testing.T) {
+	endpoint := integrationEndpoint(t)
+	svc, url := integrationQueue(t, endpoint)
+	out := filepath.Join(t.TempDir(), "out")
+
+	integrationReactor(t, map[string]any{
+		"input":     "sqs",
+		"url":       url,
+		"region":    integrationRegion,
+		"endpoint":  endpoint,
+		"accessKey": "x",
+		"secretKey": "x",
+		"output":    "cmd",
+		"cmd":       "/bin/sh",
+		"args":      []any{"-c", `echo "$1" >> ` + out + `; exit 1`, "sh", "$.id"},
+	})
+
+	if _, err := svc.SendMessage(&sqs.SendMessageInput{QueueUrl: aws.String(url), MessageBody: aws.String(`{"id":"fail"}`)}); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Eventually(t, func() bool {
+		b, _ := os.ReadFile(out)
+		return strings.TrimSpace(string(b)) == "fail"
+	}, 30*time.Second, 100*time.Millisecond)
+
+	time.Sleep(time.Second)
+	assert.Equal(t, 1, pendingMessages(t, svc, url))
+}
@@ -0,0 +1,90 @@
+package awssession
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+// Options are the settings of the connection to the AWS services
+type Options struct {
+	Region     string
+	Profile    string
+	Endpoint   string // Custom endpoint, like ElasticMQ or LocalStack
+	AccessKey  string
+	SecretKey  string
+	RoleArn    string // Role to assume with the credentials of the session
+	DisableSSL bool
+}
+
+// Set reads the option k of the configuration, it returns false if k is not
+// an option of the connection
+func (o *Options) Set(k string, v any) bool {
+	switch strings.ToLower(k) {
+	case "region":
+		o.Region, _ = v.(string)
+	case "profile":
+		o.Profile, _ = v.(string)
+	case "endpoint":
+		o.Endpoint, _ = v.(string)
+	case "accesskey":
+		o.AccessKey, _ = v.(string)
+	case "secretkey":
+		o.SecretKey, _ = v.(string)
+	case "rolearn":
+		o.RoleArn, _ = v.(string)
+	case "disablessl":
+		o.DisableSSL, _ = v.(bool)
+	default:
+		return false
+	}
+	return true
+}
+
+// New creates the session and the configuration of the clients with the
+// credentials and endpoint defined in the options. Static credentials have
+// precedence over the profile.
+func New(o Options) (*session.Session, *aws.Config, error) {
+	if (o.AccessKey == "") != (o.SecretKey == "") {
+		return nil, nil, fmt.Errorf("accessKey and secretKey must be defined together")
+	}
+
+	cfg := &aws.Config{
+		Region:     aws.String(o.Region),
+		DisableSSL: aws.Bool(o.DisableSSL),
+	}
+	if o.Endpoint != "" {
+		cfg.Endpoint = aws.String(o.Endpoint)
+	}
+	if o.AccessKey != "" {
+		cfg.Credentials = credentials.NewStaticCredentials(o.AccessKey, o.SecretKey, "")
+	}
+
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Config:            *cfg,
+		Profile:           o.Profile,
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if o.RoleArn != "" {
+		cfg.Credentials = stscreds.NewCredentials(stsSession(sess, o), o.RoleArn)
+	}
+
+	return sess, cfg, nil
+}
+
+// stsSession returns the session to assume the role, AssumeRole is sent to
+// STS and not to the custom endpoint of the service
+func stsSession(sess *session.Session, o Options) *session.Session {
+	if o.Endpoint == "" && !o.DisableSSL {
+		return sess
+	}
+	return sess.Copy(&aws.Config{Endpoint: aws.String(""), DisableSSL: aws.Bool(false)})
+}
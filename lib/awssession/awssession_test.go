@@ -0,0 +1,26 @@
+package awssession
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSTSSessionWithoutEndpoint(t *testing.T) {
+	o := Options{
+		Region:     "eu-west-1",
+		Endpoint:   "http://localhost:9324",
+		AccessKey:  "key",
+		SecretKey:  "secret",
+		DisableSSL: true,
+	}
+	sess, cfg, err := New(o)
+	assert.NoError(t, err)
+	assert.Equal(t, "http://localhost:9324", aws.StringValue(cfg.Endpoint))
+
+	sts := stsSession(sess, o)
+	assert.Equal(t, "", aws.StringValue(sts.Config.Endpoint))
+	assert.False(t, aws.BoolValue(sts.Config.DisableSSL))
+	assert.Equal(t, "eu-west-1", aws.StringValue(sts.Config.Region))
+}
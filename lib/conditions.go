@@ -0,0 +1,53 @@
+package lib
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Conditions are the regular expressions that a message must match to be
+// processed by an output, the keys are jq like paths ($.a.b) of the body or
+// ${Envelope.Name} fields
+type Conditions map[string]*regexp.Regexp
+
+// NewConditions reads the "cond" list of the configuration
+func NewConditions(v any) (Conditions, error) {
+	c := make(Conditions)
+	list, ok := v.([]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid cond %v", v)
+	}
+	for _, n := range list {
+		m, ok := n.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("invalid cond %v", n)
+		}
+		for k, expr := range m {
+			s, _ := expr.(string)
+			re, err := regexp.Compile(s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid cond %s: %w", k, err)
+			}
+			c[k] = re
+		}
+	}
+	return c, nil
+}
+
+// Match returns true if the message matches all the conditions
+func (c Conditions) Match(msg Msg) bool {
+	for k, v := range c {
+		if strings.HasPrefix(k, "$.") {
+			if !v.Match(JSONPath(msg.Body(), k)) {
+				return false
+			}
+		}
+		if name, ok := EnvelopeVariable(k); ok {
+			if !v.MatchString(EnvelopeValue(msg, name)) {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,51 @@
+package lib
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+type deadlineKey struct{}
+
+// Deadline is the time when the execution of a message must finish, the
+// outputs extend it when they start, and the keep alive of the message stops
+// after it
+type Deadline struct {
+	unixNano atomic.Int64
+}
+
+// WithDeadline returns a context with a Deadline that is not set yet
+func WithDeadline(ctx context.Context) (context.Context, *Deadline) {
+	d := &Deadline{}
+	return context.WithValue(ctx, deadlineKey{}, d), d
+}
+
+// DeadlineFrom returns the Deadline of the context, or nil
+func DeadlineFrom(ctx context.Context) *Deadline {
+	d, _ := ctx.Value(deadlineKey{}).(*Deadline)
+	return d
+}
+
+// Extend sets the deadline if it's later than the current one
+func (d *Deadline) Extend(t time.Time) {
+	if d == nil {
+		return
+	}
+	n := t.UnixNano()
+	for {
+		current := d.unixNano.Load()
+		if current >= n || d.unixNano.CompareAndSwap(current, n) {
+			return
+		}
+	}
+}
+
+// Passed returns true if the deadline was set and it's before now
+func (d *Deadline) Passed(now time.Time) bool {
+	if d == nil {
+		return false
+	}
+	n := d.unixNano.Load()
+	return n > 0 && now.UnixNano() > n
+}
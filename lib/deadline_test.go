@@ -0,0 +1,24 @@
+package lib
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeadline(t *testing.T) {
+	now := time.Now()
+	assert.Nil(t, DeadlineFrom(context.Background()))
+	assert.False(t, DeadlineFrom(context.Background()).Passed(now))
+
+	ctx, d := WithDeadline(context.Background())
+	assert.Equal(t, d, DeadlineFrom(ctx))
+	assert.False(t, d.Passed(now), "not set")
+
+	d.Extend(now.Add(time.Minute))
+	d.Extend(now.Add(time.Second)) // Earlier, it's ignored
+	assert.False(t, d.Passed(now.Add(30*time.Second)))
+	assert.True(t, d.Passed(now.Add(2*time.Minute)))
+}
@@ -22,6 +22,13 @@ type Output interface {
 	Exit()
 }
 
+// ResultOutput is implemented by the Output plugins that can return the result
+// of the execution, a JSON document or nil
+type ResultOutput interface {
+	Output
+	RunResult(ctx context.Context, rl reactorlog.ReactorLog, a Msg) ([]byte, error)
+}
+
 // LogStreams is the inteface to send logs to stram services
 type LogStream interface {
 	Send(b []byte)
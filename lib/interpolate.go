@@ -0,0 +1,138 @@
+package lib
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gabrielperezs/goreactor/reactorlog"
+)
+
+var (
+	interpolationRe = regexp.MustCompile(`\$\{(env|secret|file):([^}]+)\}`)
+
+	secretsMu sync.RWMutex
+	secrets   = make(map[string]struct{})
+)
+
+// Interpolate replaces ${env:NAME} with the value of the environment variable
+// NAME and ${file:/path} with the content of the file, without the trailing
+// new line. ${secret:NAME} is as ${env:NAME}, but the value is a secret.
+// Values read from files and ${secret:NAME} are registered as secrets.
+func Interpolate(s string) (string, error) {
+	var err error
+	r := interpolationRe.ReplaceAllStringFunc(s, func(m string) string {
+		parts := interpolationRe.FindStringSubmatch(m)
+		switch parts[1] {
+		case "env", "secret":
+			v, ok := os.LookupEnv(parts[2])
+			if !ok {
+				err = fmt.Errorf("environment variable %s is not defined", parts[2])
+			}
+			if parts[1] == "secret" {
+				AddSecret(v)
+			}
+			return v
+		default:
+			b, ferr := os.ReadFile(parts[2])
+			if ferr != nil {
+				err = ferr
+				return ""
+			}
+			v := strings.TrimRight(string(b), "\r\n")
+			AddSecret(v)
+			return v
+		}
+	})
+	if err != nil {
+		return s, err
+	}
+	return r, nil
+}
+
+// InterpolateConfig walks the configuration values and interpolates every
+// string, including the strings inside arrays and tables
+func InterpolateConfig(v any) (any, error) {
+	var err error
+	switch t := v.(type) {
+	case string:
+		return Interpolate(t)
+	case map[string]any:
+		for k, nv := range t {
+			if t[k], err = InterpolateConfig(nv); err != nil {
+				return v, err
+			}
+		}
+	case []any:
+		for i, nv := range t {
+			if t[i], err = InterpolateConfig(nv); err != nil {
+				return v, err
+			}
+		}
+	case []map[string]any:
+		for _, nv := range t {
+			if _, err = InterpolateConfig(nv); err != nil {
+				return v, err
+			}
+		}
+	}
+	return v, nil
+}
+
+// AddSecret registers a value that must never be written to the logs
+func AddSecret(s string) {
+	if s == "" {
+		return
+	}
+	secretsMu.Lock()
+	secrets[s] = struct{}{}
+	secretsMu.Unlock()
+}
+
+// Secrets returns the registered secrets
+func Secrets() []string {
+	secretsMu.RLock()
+	defer secretsMu.RUnlock()
+
+	values := make([]string, 0, len(secrets))
+	for v := range secrets {
+		values = append(values, v)
+	}
+	return values
+}
+
+// SetSecrets replaces the registered secrets, to forget the secrets of the
+// previous configuration when it's reloaded
+func SetSecrets(values []string) {
+	secretsMu.Lock()
+	secrets = make(map[string]struct{}, len(values))
+	secretsMu.Unlock()
+	for _, v := range values {
+		AddSecret(v)
+	}
+}
+
+// RedactSecrets replaces the registered secrets found in s
+func RedactSecrets(s string) string {
+	secretsMu.RLock()
+	defer secretsMu.RUnlock()
+
+	if len(secrets) == 0 {
+		return s
+	}
+
+	// Longest first, so a secret containing another one is fully redacted
+	values := make([]string, 0, len(secrets))
+	for v := range secrets {
+		values = append(values, v)
+	}
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+
+	for _, v := range values {
+		s = strings.ReplaceAll(s, v, reactorlog.RedactedValue)
+	}
+	return s
+}
@@ -0,0 +1,67 @@
+package lib
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInterpolateConfig(t *testing.T) {
+	t.Setenv("GOREACTOR_TEST_STAGE", "prod")
+
+	secretFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(secretFile, []byte("s3cr3t\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := map[string]any{
+		"url":  "https://sqs.eu-west-1.amazonaws.com/1/${env:GOREACTOR_TEST_STAGE}-queue",
+		"args": []any{"--token=${file:" + secretFile + "}", "$.path", "${CreationTimestampSeconds}"},
+		"cond": []map[string]any{{"$.Stage": "${env:GOREACTOR_TEST_STAGE}"}},
+	}
+
+	v, err := InterpolateConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := v.(map[string]any)
+	assert.Equal(t, "https://sqs.eu-west-1.amazonaws.com/1/prod-queue", c["url"])
+	assert.Equal(t, []any{"--token=s3cr3t", "$.path", "${CreationTimestampSeconds}"}, c["args"])
+	assert.Equal(t, "prod", c["cond"].([]map[string]any)[0]["$.Stage"])
+
+	assert.Equal(t, "cmd --token=[REDACTED] prod", RedactSecrets("cmd --token=s3cr3t prod"))
+}
+
+func TestInterpolateMissingEnv(t *testing.T) {
+	_, err := Interpolate("${env:GOREACTOR_TEST_UNDEFINED_VARIABLE}")
+	assert.Error(t, err)
+}
+
+func TestInterpolateSecretEnv(t *testing.T) {
+	t.Setenv("GOREACTOR_TEST_TOKEN", "t0k3n")
+	t.Setenv("GOREACTOR_TEST_REGION", "eu-west-1")
+	defer SetSecrets(Secrets())
+
+	v, err := Interpolate("--token=${secret:GOREACTOR_TEST_TOKEN} --region=${env:GOREACTOR_TEST_REGION}")
+	assert.NoError(t, err)
+	assert.Equal(t, "--token=t0k3n --region=eu-west-1", v)
+	assert.Equal(t, "--token=[REDACTED] --region=eu-west-1", RedactSecrets(v))
+
+	_, err = Interpolate("${secret:GOREACTOR_TEST_UNDEFINED_VARIABLE}")
+	assert.Error(t, err)
+}
+
+func TestSetSecrets(t *testing.T) {
+	defer SetSecrets(Secrets())
+
+	SetSecrets([]string{"old"})
+	AddSecret("new")
+	assert.ElementsMatch(t, []string{"old", "new"}, Secrets())
+
+	// Reloaded without the old secret
+	SetSecrets([]string{"new"})
+	assert.Equal(t, "old [REDACTED]", RedactSecrets("old new"))
+}
@@ -8,3 +8,39 @@ type Msg interface {
 	Done()
 	Wait()
 }
+
+// MsgAttributes is implemented by the messages that carry attributes
+// besides the body, like the SQS message attributes
+type MsgAttributes interface {
+	Attribute(name string) (string, bool)
+}
+
+// MsgRaw is implemented by the messages that were unwrapped from an envelope,
+// it returns the body as it was received
+type MsgRaw interface {
+	RawBody() []byte
+}
+
+// MsgEnvelope is implemented by the messages that were unwrapped from an
+// envelope (SNS, EventBridge...), it returns the fields of the envelope
+type MsgEnvelope interface {
+	EnvelopeValue(name string) (string, bool)
+}
+
+// MsgVariables is implemented by the messages that define variables to be
+// used as ${Name} in the arguments of the commands
+type MsgVariables interface {
+	Variable(name string) (string, bool)
+}
+
+// MsgCorrelation is implemented by the messages created from another one, like
+// the result of a command, it returns the hash of the original message
+type MsgCorrelation interface {
+	CorrelationID() string
+}
+
+// MsgChained is implemented by the messages sent by another reactor of the
+// same process, the sender keeps its execution slot while it waits for them
+type MsgChained interface {
+	Chained() bool
+}
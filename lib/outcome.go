@@ -0,0 +1,25 @@
+package lib
+
+import "time"
+
+// Actions of an Outcome
+const (
+	ActionAck        = "ack"         // The message is processed and deleted
+	ActionRetry      = "retry"       // The message is received again, after Delay if defined
+	ActionDeadLetter = "dead-letter" // The message is moved to DeadLetterQueue
+	ActionDrop       = "drop"        // The message is not deleted nor retried by goreactor
+)
+
+// Outcome is the action decided for a message by the exit code of its execution
+type Outcome struct {
+	ExitCode        int
+	Action          string
+	Delay           time.Duration // Only for retry
+	DeadLetterQueue string        // Only for dead-letter
+}
+
+// OutcomeInput is implemented by the Input plugins that can execute the
+// actions of the outcomes, besides the processed or not of Done
+type OutcomeInput interface {
+	DoneOutcome(Msg, Outcome)
+}
@@ -0,0 +1,239 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/savaki/jq"
+)
+
+var (
+	jsonPathRe         = regexp.MustCompile(`\$(\.\.|(\.[\w-]+|\.\[[\d:]+\])+)`)
+	envelopeVariableRe = regexp.MustCompile(`\$\{Envelope\.([^}]+)\}`)
+	msgVariableRe      = regexp.MustCompile(`\$\{(\w+)\}`)
+
+	templateValueRe        = regexp.MustCompile(jsonPathRe.String() + `|\$\{[^}]+\}`)
+	jsonTemplatePathRe     = regexp.MustCompile(`^` + jsonPathRe.String())
+	jsonTemplateVariableRe = regexp.MustCompile(`^\$\{[^}]+\}`)
+)
+
+// JSONPath returns the value of the jq like path ($.a.b) in the body, the
+// strings are returned without quotes
+func JSONPath(body []byte, path string) []byte {
+	op, err := jq.Parse(strings.TrimPrefix(path, "$"))
+	if err != nil {
+		return nil
+	}
+	value, _ := op.Apply(body)
+	var str string
+	if err := json.Unmarshal(value, &str); err == nil {
+		return []byte(str)
+	}
+	return value
+}
+
+// ReplacePaths replaces the jq like paths ($.a.b, or $.. for the full body) in
+// the text by their values in the body of the message, the paths can be
+// embedded in the text
+func ReplacePaths(msg Msg, s string) string {
+	if !strings.Contains(s, "$.") {
+		return s
+	}
+	return jsonPathRe.ReplaceAllStringFunc(s, func(path string) string {
+		return string(JSONPath(msg.Body(), path))
+	})
+}
+
+// TemplateFunc replaces the jq like paths and the variables of the message in
+// s like Template, every value is passed through escape
+func TemplateFunc(msg Msg, s string, escape func(string) string) string {
+	if !strings.Contains(s, "$") {
+		return s
+	}
+	return templateValueRe.ReplaceAllStringFunc(s, func(m string) string {
+		if strings.HasPrefix(m, "${") {
+			v := ReplaceVariables(msg, m)
+			if v == m {
+				return m
+			}
+			return escape(v)
+		}
+		return escape(string(JSONPath(msg.Body(), m)))
+	})
+}
+
+// URLTemplate replaces the jq like paths and the variables of the message in
+// the URL s, the values are escaped for the path or for the query
+func URLTemplate(msg Msg, s string) string {
+	path, query, found := strings.Cut(s, "?")
+	path = TemplateFunc(msg, path, url.PathEscape)
+	if !found {
+		return path
+	}
+	return path + "?" + TemplateFunc(msg, query, url.QueryEscape)
+}
+
+// BodyTemplate replaces the template of a body, with JSONTemplate if the body
+// is a JSON document (it starts with { or [) and with Template otherwise
+func BodyTemplate(msg Msg, s string) string {
+	b := strings.TrimSpace(s)
+	if strings.HasPrefix(b, "{") || strings.HasPrefix(b, "[") {
+		return JSONTemplate(msg, s)
+	}
+	return Template(msg, s)
+}
+
+// JSONTemplate replaces the jq like paths and the variables of the message in
+// the JSON document s, the values are encoded as JSON. A path or a variable
+// that is a whole string ("$.a") or out of a string ($.a) is replaced by its
+// JSON value, and inside a string ("id-$.a") by the escaped string.
+func JSONTemplate(msg Msg, s string) string {
+	if !strings.Contains(s, "$") {
+		return s
+	}
+
+	out := make([]byte, 0, len(s))
+	inString, escaped := false, false
+	stringStart := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case escaped:
+			escaped = false
+		case inString && c == '\\':
+			escaped = true
+		case c == '"':
+			inString = !inString
+			stringStart = i
+		case c == '$':
+			var value []byte // JSON value
+			m := jsonTemplatePathRe.FindString(s[i:])
+			if m != "" {
+				value = rawJSONPath(msg.Body(), m)
+			} else if m = jsonTemplateVariableRe.FindString(s[i:]); m != "" {
+				v := ReplaceVariables(msg, m)
+				if _, err := strconv.ParseFloat(v, 64); err == nil && !inString {
+					value = []byte(v)
+				} else {
+					value = marshalJSONString(v)
+				}
+			}
+			if m == "" {
+				break
+			}
+
+			end := i + len(m)
+			switch {
+			case inString && stringStart == i-1 && end < len(s) && s[end] == '"':
+				// The whole string, the quotes are replaced
+				out = append(out[:len(out)-1], value...)
+				inString = false
+				end++
+			case inString:
+				q := marshalJSONString(jsonString(value))
+				out = append(out, q[1:len(q)-1]...) // Without the quotes
+			default:
+				out = append(out, value...)
+			}
+			i = end
+			continue
+		}
+		out = append(out, c)
+		i++
+	}
+	return string(out)
+}
+
+// rawJSONPath returns the JSON value of the path in the body, null if the
+// path doesn't exist
+func rawJSONPath(body []byte, path string) []byte {
+	op, err := jq.Parse(strings.TrimPrefix(path, "$"))
+	if err != nil {
+		return []byte("null")
+	}
+	value, err := op.Apply(body)
+	if err != nil || len(value) == 0 {
+		return []byte("null")
+	}
+	return value
+}
+
+// jsonString returns the string of a JSON value, the JSON text if it isn't
+// a string
+func jsonString(value []byte) string {
+	var s string
+	if err := json.Unmarshal(value, &s); err != nil {
+		return string(value)
+	}
+	return s
+}
+
+func marshalJSONString(s string) []byte {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return bytes.TrimRight(b.Bytes(), "\n")
+}
+
+// ReplaceVariables replaces the ${CreationTimestampMilliseconds},
+// ${CreationTimestampSeconds}, ${Envelope.Name} and the variables
+// defined by the message (MsgVariables)
+func ReplaceVariables(msg Msg, s string) string {
+	if !strings.Contains(s, "${") {
+		return s
+	}
+
+	s = strings.ReplaceAll(s, "${CreationTimestampMilliseconds}",
+		strconv.FormatInt(msg.CreationTimestampMilliseconds(), 10))
+
+	if strings.Contains(s, "${CreationTimestampSeconds}") {
+		var milliSecondsInSecond int64 = 1000
+		s = strings.ReplaceAll(s, "${CreationTimestampSeconds}",
+			strconv.FormatInt(msg.CreationTimestampMilliseconds()/milliSecondsInSecond, 10))
+	}
+
+	if v, ok := msg.(MsgVariables); ok {
+		s = msgVariableRe.ReplaceAllStringFunc(s, func(m string) string {
+			if value, ok := v.Variable(m[2 : len(m)-1]); ok {
+				return value
+			}
+			return m
+		})
+	}
+
+	if strings.Contains(s, "${Envelope.") {
+		s = envelopeVariableRe.ReplaceAllStringFunc(s, func(m string) string {
+			return EnvelopeValue(msg, envelopeVariableRe.FindStringSubmatch(m)[1])
+		})
+	}
+	return s
+}
+
+// Template replaces the jq like paths and the variables of the message in s
+func Template(msg Msg, s string) string {
+	return ReplaceVariables(msg, ReplacePaths(msg, s))
+}
+
+// EnvelopeVariable returns the name of the field if s contains ${Envelope.Name}
+func EnvelopeVariable(s string) (string, bool) {
+	m := envelopeVariableRe.FindStringSubmatch(s)
+	if m == nil {
+		return "", false
+	}
+	return m[1], true
+}
+
+// EnvelopeValue returns the field of the envelope of the message, empty if the
+// message had no envelope or the field doesn't exist
+func EnvelopeValue(msg Msg, name string) string {
+	if e, ok := msg.(MsgEnvelope); ok {
+		v, _ := e.EnvelopeValue(name)
+		return v
+	}
+	return ""
+}
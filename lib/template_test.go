@@ -0,0 +1,96 @@
+package lib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testMsg struct {
+	body      []byte
+	ts        int64
+	variables map[string]string
+}
+
+func (m *testMsg) Body() []byte {
+	return m.body
+}
+
+func (m *testMsg) CreationTimestampMilliseconds() int64 {
+	return m.ts
+}
+
+func (m *testMsg) GetHash() string {
+	return ""
+}
+
+func (m *testMsg) Done() {
+}
+
+func (m *testMsg) Wait() {
+}
+
+func (m *testMsg) Variable(name string) (string, bool) {
+	v, ok := m.variables[name]
+	return v, ok
+}
+
+func TestTemplate(t *testing.T) {
+	msg := &testMsg{
+		body:      []byte(`{"id":"a1","items":[{"n":1},{"n":2}],"user":{"name":"bob"}}`),
+		ts:        1591784694123,
+		variables: map[string]string{"S3Key": "a/b.txt"},
+	}
+
+	assert.Equal(t, "a1", Template(msg, "$.id"))
+	assert.Equal(t, `{"msg":{"id":"a1","items":[{"n":1},{"n":2}],"user":{"name":"bob"}}}`, Template(msg, `{"msg":$..}`))
+	assert.Equal(t, "/users/bob/items/a1", Template(msg, "/users/$.user.name/items/$.id"))
+	assert.Equal(t, `{"id":"a1","n":2}`, Template(msg, `{"id":"$.id","n":$.items.[1].n}`))
+	assert.Equal(t, "1591784694-1591784694123", Template(msg, "${CreationTimestampSeconds}-${CreationTimestampMilliseconds}"))
+	assert.Equal(t, "a/b.txt ${Other}", Template(msg, "${S3Key} ${Other}"))
+	assert.Equal(t, "", Template(msg, "${Envelope.Subject}"))
+}
+
+func TestJSONTemplate(t *testing.T) {
+	msg := &testMsg{
+		body:      []byte(`{"id":"a\"1","n":2,"items":[1,2],"html":"<b>"}`),
+		ts:        1591784694123,
+		variables: map[string]string{"S3Key": "a/\"b.txt"},
+	}
+
+	assert.Equal(t, `{"id":"a\"1","n":2,"items":[1,2]}`, JSONTemplate(msg, `{"id":"$.id","n":$.n,"items":$.items}`))
+	assert.Equal(t, `{"id":"id:a\"1/2","missing":null}`, JSONTemplate(msg, `{"id":"id:$.id/$.n","missing":"$.missing"}`))
+	assert.Equal(t, `{"key":"a/\"b.txt","in":"s3://a/\"b.txt","ts":1591784694}`,
+		JSONTemplate(msg, `{"key":"${S3Key}","in":"s3://${S3Key}","ts":${CreationTimestampSeconds}}`))
+	assert.Equal(t, `{"html":"<b>","q":"\"a\"1\""}`, JSONTemplate(msg, `{"html":"$.html","q":"\"$.id\""}`))
+}
+
+func TestURLTemplate(t *testing.T) {
+	msg := &testMsg{
+		body:      []byte(`{"id":"../admin?x=1","q":"a&b=c d","user":{"name":"bob"}}`),
+		variables: map[string]string{"S3Key": "a/b c.txt"},
+	}
+
+	assert.Equal(t, "https://api/users/bob/items/..%2Fadmin%3Fx=1?q=a%26b%3Dc+d&key=a%2Fb+c.txt",
+		URLTemplate(msg, "https://api/users/$.user.name/items/$.id?q=$.q&key=${S3Key}"))
+	assert.Equal(t, "https://api/a%2Fb%20c.txt", URLTemplate(msg, "https://api/${S3Key}"))
+}
+
+func TestJSONPath(t *testing.T) {
+	body := []byte(`{"s":"a \"b\"","n":1,"o":{"a":1}}`)
+	assert.Equal(t, `a "b"`, string(JSONPath(body, "$.s")))
+	assert.Equal(t, "1", string(JSONPath(body, "$.n")))
+	assert.Equal(t, `{"a":1}`, string(JSONPath(body, "$.o")))
+}
+
+func TestConditions(t *testing.T) {
+	c, err := NewConditions([]any{map[string]any{"$.user.name": "^b", "$.id": "1$"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, c.Match(&testMsg{body: []byte(`{"id":"a1","user":{"name":"bob"}}`)}))
+	assert.False(t, c.Match(&testMsg{body: []byte(`{"id":"a2","user":{"name":"bob"}}`)}))
+
+	_, err = NewConditions([]any{map[string]any{"$.id": "("}})
+	assert.Error(t, err)
+}
@@ -1,14 +1,18 @@
 package awsfirehose
 
 import (
+	"fmt"
 	"reflect"
 	"strings"
 
+	"github.com/aws/aws-sdk-go/service/firehose"
+	"github.com/gabrielperezs/goreactor/lib/awssession"
 	firehosePool "github.com/gabrielperezs/streamspooler/firehose"
 )
 
 type AWSFirehose struct {
 	s *firehosePool.Server
+	w *writer // With accessKey, secretKey or roleArn
 }
 
 func NewOrGet(cfg map[string]any) (*AWSFirehose, error) {
@@ -23,6 +27,25 @@ func NewOrGet(cfg map[string]any) (*AWSFirehose, error) {
 		cpCfg[strings.ToLower(k)] = v
 	}
 
+	// The spooler creates the session from the profile or the default
+	// credentials chain, the other credentials are used by the writer
+	options := awssession.Options{}
+	for k, v := range cpCfg {
+		options.Set(k, v)
+	}
+	if options.AccessKey != "" || options.SecretKey != "" || options.RoleArn != "" {
+		return newWithCredentials(options, cpCfg)
+	}
+
+	// Without SSL the endpoint must be http
+	if disableSSL, _ := cpCfg["disablessl"].(bool); disableSSL {
+		endpoint, _ := cpCfg["endpoint"].(string)
+		if endpoint == "" {
+			return nil, fmt.Errorf("Firehose ERROR: disableSSL requires an endpoint")
+		}
+		cpCfg["endpoint"] = "http://" + strings.TrimPrefix(strings.TrimPrefix(endpoint, "https://"), "http://")
+	}
+
 	for i := 0; i < ps.NumField(); i++ {
 		newValue, ok := cpCfg[strings.ToLower(typeOfS.Field(i).Name)]
 		if !ok {
@@ -62,10 +85,48 @@ func NewOrGet(cfg map[string]any) (*AWSFirehose, error) {
 	return o, nil
 }
 
+// spoolerOnly are the options of the spooler that the writer doesn't support
+var spoolerOnly = []string{"compress", "concatrecords", "maxrecords", "critical"}
+
+// newWithCredentials sends the records with the session of the options
+func newWithCredentials(options awssession.Options, cfg map[string]any) (*AWSFirehose, error) {
+	stream, _ := cfg["streamname"].(string)
+	if stream == "" {
+		return nil, fmt.Errorf("Firehose ERROR: streamname not found or invalid")
+	}
+	for _, k := range spoolerOnly {
+		if _, ok := cfg[k]; ok {
+			return nil, fmt.Errorf("Firehose ERROR: %s is not supported with accessKey, secretKey or roleArn", k)
+		}
+	}
+
+	sess, awsCfg, err := awssession.New(options)
+	if err != nil {
+		return nil, fmt.Errorf("Firehose ERROR: %w", err)
+	}
+
+	buffer := 0
+	switch v := cfg["buffer"].(type) {
+	case int:
+		buffer = v
+	case int64:
+		buffer = int(v)
+	}
+	return &AWSFirehose{w: newWriter(firehose.New(sess, awsCfg), stream, buffer)}, nil
+}
+
 func (o *AWSFirehose) Send(b []byte) {
+	if o.w != nil {
+		o.w.Send(b)
+		return
+	}
 	o.s.C <- b
 }
 
 func (o *AWSFirehose) Exit() {
+	if o.w != nil {
+		o.w.Exit()
+		return
+	}
 	o.s.Exit()
 }
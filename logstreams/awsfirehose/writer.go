@@ -0,0 +1,148 @@
+package awsfirehose
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/firehose"
+	"github.com/aws/aws-sdk-go/service/firehose/firehoseiface"
+)
+
+const (
+	defaultBuffer   = 1024
+	flushInterval   = 15 * time.Second
+	maxRecordSize   = 1000 * 1000 // The maximum size of a record, before base64-encoding
+	maxBatchRecords = 500         // The maximum records of a PutRecordBatch
+	maxBatchSize    = 3 << 20     // Under the 4 MiB of a PutRecordBatch
+	maxRetries      = 3
+	retryWait       = 500 * time.Millisecond
+	requestTimeout  = 30 * time.Second
+)
+
+// writer sends the lines to the stream with the credentials of the session,
+// the spooler only uses the profile or the default credentials chain
+type writer struct {
+	sync.RWMutex
+	svc     firehoseiface.FirehoseAPI
+	stream  string
+	c       chan []byte
+	done    chan struct{}
+	exiting bool
+}
+
+func newWriter(svc firehoseiface.FirehoseAPI, stream string, buffer int) *writer {
+	if buffer <= 0 {
+		buffer = defaultBuffer
+	}
+	w := &writer{
+		svc:    svc,
+		stream: stream,
+		c:      make(chan []byte, buffer),
+		done:   make(chan struct{}),
+	}
+	go w.listen()
+	return w
+}
+
+func (w *writer) listen() {
+	defer close(w.done)
+
+	t := time.NewTicker(flushInterval)
+	defer t.Stop()
+
+	var batch [][]byte
+	size := 0
+	for {
+		select {
+		case b, ok := <-w.c:
+			if !ok {
+				w.flush(batch)
+				return
+			}
+			if len(b)+1 > maxRecordSize {
+				log.Printf("Firehose ERROR: one record is over the limit %d/%d", len(b)+1, maxRecordSize)
+				continue
+			}
+			if len(batch) >= maxBatchRecords || size+len(b)+1 > maxBatchSize {
+				w.flush(batch)
+				batch, size = nil, 0
+			}
+			r := make([]byte, 0, len(b)+1)
+			r = append(append(r, b...), '\n')
+			batch = append(batch, r)
+			size += len(r)
+		case <-t.C:
+			w.flush(batch)
+			batch, size = nil, 0
+		}
+	}
+}
+
+// flush sends the records, the ones that failed are retried
+func (w *writer) flush(batch [][]byte) {
+	for try := 0; len(batch) > 0 && try < maxRetries; try++ {
+		if try > 0 {
+			time.Sleep(retryWait)
+		}
+
+		records := make([]*firehose.Record, len(batch))
+		for i, b := range batch {
+			records[i] = &firehose.Record{Data: b}
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+		out, err := w.svc.PutRecordBatchWithContext(ctx, &firehose.PutRecordBatchInput{
+			DeliveryStreamName: aws.String(w.stream),
+			Records:            records,
+		})
+		cancel()
+		if err != nil {
+			log.Printf("Firehose ERROR: PutRecordBatch %s: %s", w.stream, err)
+			continue
+		}
+		if aws.Int64Value(out.FailedPutCount) == 0 {
+			return
+		}
+
+		var failed [][]byte
+		for i, r := range out.RequestResponses {
+			if r != nil && r.ErrorCode != nil && i < len(batch) {
+				failed = append(failed, batch[i])
+			}
+		}
+		log.Printf("Firehose ERROR: PutRecordBatch %s: %d records failed", w.stream, len(failed))
+		batch = failed
+	}
+
+	if len(batch) > 0 {
+		log.Printf("Firehose ERROR: %d records lost in %s", len(batch), w.stream)
+	}
+}
+
+// Send queues the record, the records sent after Exit are dropped
+func (w *writer) Send(b []byte) {
+	w.RLock()
+	defer w.RUnlock()
+	if w.exiting {
+		return
+	}
+	w.c <- b
+}
+
+// Exit sends the pending records, it's called by every reactor sharing the
+// log stream
+func (w *writer) Exit() {
+	w.Lock()
+	if w.exiting {
+		w.Unlock()
+		<-w.done
+		return
+	}
+	w.exiting = true
+	close(w.c)
+	w.Unlock()
+	<-w.done
+}
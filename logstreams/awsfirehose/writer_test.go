@@ -0,0 +1,75 @@
+package awsfirehose
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/firehose"
+	"github.com/aws/aws-sdk-go/service/firehose/firehoseiface"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeFirehose fails the first record of the first request
+type fakeFirehose struct {
+	firehoseiface.FirehoseAPI
+	mu       sync.Mutex
+	requests int
+	records  []string
+}
+
+func (f *fakeFirehose) PutRecordBatchWithContext(ctx aws.Context, in *firehose.PutRecordBatchInput, opts ...request.Option) (*firehose.PutRecordBatchOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests++
+
+	out := &firehose.PutRecordBatchOutput{FailedPutCount: aws.Int64(0)}
+	for i, r := range in.Records {
+		if f.requests == 1 && i == 0 {
+			out.FailedPutCount = aws.Int64(1)
+			out.RequestResponses = append(out.RequestResponses, &firehose.PutRecordBatchResponseEntry{ErrorCode: aws.String("ServiceUnavailableException")})
+			continue
+		}
+		f.records = append(f.records, string(r.Data))
+		out.RequestResponses = append(out.RequestResponses, &firehose.PutRecordBatchResponseEntry{RecordId: aws.String("id")})
+	}
+	return out, nil
+}
+
+func TestWriterRetriesFailedRecords(t *testing.T) {
+	svc := &fakeFirehose{}
+	w := newWriter(svc, "logs", 10)
+	w.Send([]byte(`{"a":1}`))
+	w.Send([]byte(`{"b":2}`))
+	w.Exit()
+
+	assert.Equal(t, 2, svc.requests)
+	assert.Equal(t, []string{"{\"b\":2}\n", "{\"a\":1}\n"}, svc.records)
+}
+
+func TestWriterExitTwice(t *testing.T) {
+	svc := &fakeFirehose{requests: 1}
+	w := newWriter(svc, "logs", 10)
+	w.Send([]byte(`{"a":1}`))
+
+	// Every reactor sharing the log stream calls Exit
+	w.Exit()
+	w.Exit()
+	w.Send([]byte(`{"b":2}`))
+
+	assert.Equal(t, []string{"{\"a\":1}\n"}, svc.records)
+}
+
+func TestNewWithCredentials(t *testing.T) {
+	_, err := NewOrGet(map[string]any{"accessKey": "key", "region": "eu-west-1", "streamName": "logs"})
+	assert.Error(t, err, "secretKey is required")
+
+	o, err := NewOrGet(map[string]any{"accessKey": "key", "secretKey": "secret", "region": "eu-west-1", "streamName": "logs"})
+	assert.NoError(t, err)
+	assert.NotNil(t, o.w)
+	o.Exit()
+
+	_, err = NewOrGet(map[string]any{"roleArn": "arn:aws:iam::1:role/logs", "region": "eu-west-1", "streamName": "logs", "compress": true})
+	assert.ErrorContains(t, err, "compress is not supported")
+}
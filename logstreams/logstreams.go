@@ -30,10 +30,8 @@ func Get(cfg any) (lib.LogStream, error) {
 			case "", "none":
 				return nil, fmt.Errorf("WARNING: logstream is disabled")
 			default:
-				return nil, fmt.Errorf("ERROR: logstream plugin %s doesn't exist", k)
+				return nil, fmt.Errorf("ERROR: logstream plugin %s doesn't exist", v)
 			}
-		default:
-			return nil, fmt.Errorf("Plugin don't exists: %s", k)
 		}
 	}
 
@@ -1,17 +1,19 @@
 package main
 
 import (
+	_ "expvar" // Metrics in /debug/vars
 	"flag"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"sync"
 	"syscall"
 
-	"github.com/BurntSushi/toml"
 	"github.com/gabrielperezs/goreactor/inputs"
+	"github.com/gabrielperezs/goreactor/lib"
 	"github.com/gabrielperezs/goreactor/logstreams"
 	"github.com/gabrielperezs/goreactor/outputs"
 	"github.com/gabrielperezs/goreactor/reactor"
@@ -22,8 +24,11 @@ import (
 // by all the plugins and filled with the TOML parser
 type Config struct {
 	MaxConcurrency int
+	MetricsListen  string // Address to serve the metrics in /debug/vars
 	LogStream      any
 	Reactor        []any
+
+	secrets []string // Registered by the interpolation of the configuration
 }
 
 var (
@@ -48,6 +53,7 @@ func main() {
 
 	go sing()
 	reload()
+	startMetrics()
 	start()
 
 	<-chMain
@@ -73,15 +79,19 @@ func start() {
 		nr.SetHostname(hostname)
 		nr.SetConcurrencyControl(dynsem)
 
+		// The output first, the input starts delivering messages as soon as it's created
 		var err error
-		nr.I, err = inputs.Get(nr, r)
+		nr.O, err = outputs.Get(nr, r)
 		if err != nil {
 			log.Printf("ERROR: %s %s", r, err)
+			continue
 		}
 
-		nr.O, err = outputs.Get(nr, r)
+		nr.I, err = inputs.Get(nr, r)
 		if err != nil {
 			log.Printf("ERROR: %s %s", r, err)
+			nr.O.Exit()
+			continue
 		}
 
 		nr.Start()
@@ -89,6 +99,20 @@ func start() {
 	}
 }
 
+// startMetrics serves the expvar metrics, only once because the
+// listen address can't be changed with a reload
+func startMetrics() {
+	if conf.MetricsListen == "" {
+		return
+	}
+	log.Printf("Metrics listening on %s/debug/vars", conf.MetricsListen)
+	go func() {
+		if err := http.ListenAndServe(conf.MetricsListen, nil); err != nil {
+			log.Printf("ERROR metrics: %s", err)
+		}
+	}()
+}
+
 func exit() {
 	for _, r := range running {
 		r.Stop() // To force to stop receiving input (SQS)
@@ -107,15 +131,23 @@ func restart() {
 		r.Exit()
 	}
 	running = nil
+	lib.SetSecrets(conf.secrets) // Forget the secrets of the previous configuration
 	start()
 }
 
 func reload() {
+	// The running reactors keep the secrets of the previous configuration
+	// until they are restarted
+	previous := lib.Secrets()
+	lib.SetSecrets(nil)
 	c, err := readConfig(configFile, configDir)
 	if err != nil {
+		lib.SetSecrets(previous)
 		log.Printf("ERROR reading config file or directory %s,%s: %s", configFile, configDir, err)
 		return
 	}
+	c.secrets = lib.Secrets()
+	lib.SetSecrets(append(previous, c.secrets...))
 	mu.Lock()
 	conf = *c
 	mu.Unlock()
@@ -147,9 +179,12 @@ func readConfig(configFile, configDir string) (c *Config, err error) {
 	}
 
 	if configDir == "" {
-		//Configuration is a file
-		_, err = toml.DecodeFile(configFile, &c)
-		return c, err
+		//Configuration is a file, TOML unless the extension says otherwise
+		format, ok := configFormat(configFile)
+		if !ok {
+			format = "toml"
+		}
+		return decodeConfigFile(configFile, format)
 	}
 
 	//Configuration is a directory
@@ -172,14 +207,15 @@ func readConfig(configFile, configDir string) (c *Config, err error) {
 			continue
 		}
 
-		if filepath.Ext(file.Name()) != ".conf" {
+		format, ok := configFormat(file.Name())
+		if !ok {
 			continue
 		}
 
-		pathTemp := fmt.Sprintf("%s/%s", configDir, file.Name())
+		pathTemp := filepath.Join(configDir, file.Name())
 
-		var configTemp *Config
-		if _, err := toml.DecodeFile(pathTemp, &configTemp); err != nil {
+		configTemp, err := decodeConfigFile(pathTemp, format)
+		if err != nil {
 			log.Printf("ERROR reading config file %s: %s", pathTemp, err)
 			continue
 		}
@@ -191,6 +227,11 @@ func readConfig(configFile, configDir string) (c *Config, err error) {
 			c.LogStream = configTemp.LogStream
 		}
 
+		// Read first MetricsListen only
+		if c.MetricsListen == "" && configTemp.MetricsListen != "" {
+			c.MetricsListen = configTemp.MetricsListen
+		}
+
 		// Read first MaxConcurrency only
 		if c.MaxConcurrency == 0 && configTemp.MaxConcurrency != 0 {
 			c.MaxConcurrency = configTemp.MaxConcurrency
@@ -0,0 +1,75 @@
+package outputs
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/gabrielperezs/goreactor/inputs/chain"
+	"github.com/gabrielperezs/goreactor/lib"
+	"github.com/gabrielperezs/goreactor/reactor"
+	"github.com/gabrielperezs/goreactor/reactorlog"
+)
+
+// Chain is the output that sends the messages to other reactor of the same
+// process, with input "reactor"
+type Chain struct {
+	r    *reactor.Reactor
+	name string
+	cond lib.Conditions
+}
+
+func newChain(r *reactor.Reactor, c map[string]any) (*Chain, error) {
+	o := &Chain{
+		r: r,
+	}
+
+	for k, v := range c {
+		switch strings.ToLower(k) {
+		case "reactorname":
+			o.name, _ = v.(string)
+		case "cond":
+			var err error
+			if o.cond, err = lib.NewConditions(v); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if o.name == "" {
+		return nil, fmt.Errorf("Can't read the configuration (hint: reactorName)")
+	}
+	return o, nil
+}
+
+// MatchConditions is a filter of the messages that will be sent
+func (o *Chain) MatchConditions(msg lib.Msg) error {
+	if !o.cond.Match(msg) {
+		return reactor.ErrInvalidMsgForPlugin
+	}
+	return nil
+}
+
+// Run sends the message to the reactor and waits until it's processed, the
+// message is processed only if the reactor succeeded
+func (o *Chain) Run(ctx context.Context, rl reactorlog.ReactorLog, msg lib.Msg) error {
+	if o.r != nil {
+		rl.SetLabel(lib.Template(msg, o.r.Label))
+	}
+	rl.SetHash(msg.GetHash())
+	rl.Start(0, "reactor "+o.name)
+
+	err := chain.Send(ctx, o.name, msg)
+	switch err {
+	case nil:
+	case reactor.ErrInvalidMsgForPlugin:
+		rl.Write([]byte("the message doesn't match the conditions of reactor " + o.name + "\n"))
+	default:
+		rl.Write([]byte("error: " + err.Error() + "\n"))
+	}
+	return err
+}
+
+// Exit will finish the output
+func (o *Chain) Exit() {
+}
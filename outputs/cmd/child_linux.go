@@ -0,0 +1,247 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+const (
+	childSpecEnv   = "GOREACTOR_CMD_CHILD"
+	childErrorCode = 127
+)
+
+// executable is goreactor, it starts the commands that need to prepare the
+// process before executing the command
+var executable = sync.OnceValues(os.Executable)
+
+// childSpec is what the child does before executing the command, it's sent
+// to the child in the environment
+type childSpec struct {
+	Path    string
+	Files   int           // Descriptors inherited by the command, stdio and the extra files. The init writes the status in the next one.
+	Rlimits []childRlimit `json:",omitempty"`
+	Sandbox *childSandbox `json:",omitempty"`
+}
+
+type childRlimit struct {
+	Resource int
+	Value    uint64
+}
+
+// init runs the child, if goreactor was started to execute a command
+func init() {
+	s, ok := os.LookupEnv(childSpecEnv)
+	if !ok {
+		return
+	}
+	os.Unsetenv(childSpecEnv)
+
+	if err := runChild(s); err != nil {
+		fmt.Fprintf(os.Stderr, "goreactor: %s\n", err)
+		os.Exit(childErrorCode)
+	}
+}
+
+func runChild(s string) error {
+	var spec childSpec
+	if err := json.Unmarshal([]byte(s), &spec); err != nil {
+		return fmt.Errorf("invalid child: %w", err)
+	}
+
+	if spec.Sandbox != nil {
+		if err := spec.Sandbox.setup(); err != nil {
+			return fmt.Errorf("sandbox: %w", err)
+		}
+	}
+
+	for _, r := range spec.Rlimits {
+		var current syscall.Rlimit
+		if err := syscall.Getrlimit(r.Resource, &current); err != nil {
+			return fmt.Errorf("getrlimit %d: %w", r.Resource, err)
+		}
+		// The limits can't be raised over the hard limit
+		rlim := syscall.Rlimit{Cur: min(r.Value, current.Max), Max: min(r.Value, current.Max)}
+		if err := syscall.Setrlimit(r.Resource, &rlim); err != nil {
+			return fmt.Errorf("setrlimit %d: %w", r.Resource, err)
+		}
+	}
+
+	if spec.Sandbox != nil {
+		if err := spec.Sandbox.dropCredential(); err != nil {
+			return fmt.Errorf("sandbox: %w", err)
+		}
+	}
+
+	// In a chroot the command wasn't searched yet
+	path, err := exec.LookPath(spec.Path)
+	if err != nil {
+		return err
+	}
+	if spec.Sandbox != nil && spec.Sandbox.Init {
+		return runInit(path, spec.Files)
+	}
+	err = syscall.Exec(path, os.Args, os.Environ())
+	return fmt.Errorf("exec %s: %w", path, err)
+}
+
+// runInit starts the command as the init of the PID namespace. The process 1
+// ignores the signals it doesn't handle, so the init forwards the signals to
+// all the processes of the namespace and reaps the orphans. When the command
+// exits the init exits with its status and the kernel kills the rest of the
+// processes of the namespace. The init can't terminate with the signal that
+// terminated the command, the process 1 ignores even its own signals, so it
+// writes the signal in the status descriptor for goreactor.
+func runInit(path string, files int) error {
+	sigs := make(chan os.Signal, 16)
+	signal.Notify(sigs)
+
+	fds := make([]uintptr, files)
+	for i := range fds {
+		fds[i] = uintptr(i)
+	}
+	syscall.CloseOnExec(files) // The status is only for the init
+	// The command has its own process group, the signals sent to the group
+	// of the init are forwarded just once
+	pid, err := syscall.ForkExec(path, os.Args, &syscall.ProcAttr{
+		Env:   os.Environ(),
+		Files: fds,
+		Sys:   &syscall.SysProcAttr{Setpgid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("exec %s: %w", path, err)
+	}
+
+	for sig := range sigs {
+		switch sig {
+		case syscall.SIGCHLD:
+		case syscall.SIGURG: // Used by the go runtime
+			continue
+		default:
+			syscall.Kill(-1, sig.(syscall.Signal))
+			continue
+		}
+
+		for {
+			var status syscall.WaitStatus
+			wpid, err := syscall.Wait4(-1, &status, syscall.WNOHANG, nil)
+			if err != nil || wpid <= 0 {
+				break
+			}
+			if wpid != pid {
+				continue
+			}
+			if status.Signaled() {
+				f := os.NewFile(uintptr(files), "status")
+				f.Write([]byte{byte(status.Signal())})
+				os.Exit(128 + int(status.Signal()))
+			}
+			os.Exit(status.ExitStatus())
+		}
+	}
+	return nil
+}
+
+// prepareProcess applies the limits and the sandbox to the command, the
+// process is started by the child if they need it. The cgroup and the status
+// of the init must be closed after the execution.
+func (o *Cmd) prepareProcess(c *exec.Cmd) (*cgroup, *initStatus, error) {
+	spec := &childSpec{}
+	cg, err := o.applyLimits(c, spec)
+	if err != nil {
+		return nil, nil, err
+	}
+	o.sandbox.apply(c, spec)
+
+	if len(spec.Rlimits) == 0 && spec.Sandbox == nil {
+		return cg, nil, nil
+	}
+	st, err := spec.wrap(c)
+	if err != nil {
+		cg.close()
+		return nil, nil, err
+	}
+	return cg, st, nil
+}
+
+// wrap starts the command through goreactor, that prepares the process as
+// defined in the spec before executing the command
+func (spec *childSpec) wrap(c *exec.Cmd) (*initStatus, error) {
+	self, err := executable()
+	if err != nil {
+		return nil, err
+	}
+	spec.Path = c.Path
+	spec.Files = 3 + len(c.ExtraFiles)
+	b, err := json.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	var st *initStatus
+	if spec.Sandbox != nil && spec.Sandbox.Init {
+		if st, err = newInitStatus(c); err != nil {
+			return nil, err
+		}
+	}
+
+	c.Path = self
+	if c.Env == nil {
+		c.Env = os.Environ()
+	}
+	c.Env = append(c.Env, childSpecEnv+"="+string(b))
+	return st, nil
+}
+
+// initStatus receives from the init of the PID namespace the signal that
+// terminated the command
+type initStatus struct {
+	r, w *os.File
+}
+
+func newInitStatus(c *exec.Cmd) (*initStatus, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	c.ExtraFiles = append(c.ExtraFiles, w)
+	return &initStatus{r: r, w: w}, nil
+}
+
+// started is called after the start of the command, only the init keeps
+// the status open
+func (st *initStatus) started() {
+	if st == nil || st.w == nil {
+		return
+	}
+	st.w.Close()
+	st.w = nil
+}
+
+// signal returns the signal that terminated the command, once the init
+// exited
+func (st *initStatus) signal() (syscall.Signal, bool) {
+	if st == nil || st.w != nil {
+		return 0, false
+	}
+	b, _ := io.ReadAll(io.LimitReader(st.r, 2))
+	if len(b) != 1 {
+		return 0, false
+	}
+	return syscall.Signal(b[0]), true
+}
+
+func (st *initStatus) close() {
+	if st == nil {
+		return
+	}
+	if st.w != nil {
+		st.w.Close()
+	}
+	st.r.Close()
+}
@@ -1,14 +1,15 @@
 package cmd
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"log"
 	"os/exec"
-	"regexp"
 	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/gabrielperezs/goreactor/lib"
@@ -30,8 +31,23 @@ type Cmd struct {
 	workingDirectory   string
 	environment        []string
 	args               []string
-	cond               map[string]*regexp.Regexp
+	cond               lib.Conditions
+	validations        []*argRule
+	rejectLeadingDash  bool
+	result             string // Source of the JSON result of the command
 	maximumCmdTimeLive time.Duration
+	stopSignal         syscall.Signal // Sent to the process group on timeout or shutdown
+	stopSignalName     string
+	stopGracePeriod    time.Duration // Before SIGKILL
+	timeoutFrom        string        // Path of the timeout in the message, capped by maximumCmdTimeLive
+	limits             *limits
+	sandbox            *sandbox
+
+	mu       sync.Mutex
+	exiting  bool
+	running  sync.WaitGroup // Executions and process groups being stopped
+	stopping context.Context
+	shutdown context.CancelFunc
 }
 
 // NewOrGet create the command struct and fill the parameters needed from the
@@ -39,9 +55,11 @@ type Cmd struct {
 func NewOrGet(r *reactor.Reactor, c map[string]any) (*Cmd, error) {
 
 	o := &Cmd{
-		r:    r,
-		cond: make(map[string]*regexp.Regexp),
+		r:               r,
+		stopSignalName:  defaultStopSignal,
+		stopGracePeriod: defaultStopGracePeriod,
 	}
+	o.stopping, o.shutdown = context.WithCancel(context.Background())
 
 	for k, v := range c {
 		switch strings.ToLower(k) {
@@ -60,11 +78,25 @@ func NewOrGet(r *reactor.Reactor, c map[string]any) (*Cmd, error) {
 				o.environment = append(o.environment, n.(string))
 			}
 		case "cond":
-			for _, v := range v.([]any) {
-				for nk, nv := range v.(map[string]any) {
-					o.cond[nk] = regexp.MustCompile(nv.(string))
+			var err error
+			if o.cond, err = lib.NewConditions(v); err != nil {
+				return nil, fmt.Errorf("CMD ERROR: %w", err)
+			}
+		case "validate":
+			for _, n := range v.([]any) {
+				rule, err := newArgRule(n.(map[string]any))
+				if err != nil {
+					return nil, fmt.Errorf("CMD ERROR: invalid validate rule: %w", err)
 				}
-
+				o.validations = append(o.validations, rule)
+			}
+		case "rejectleadingdash":
+			o.rejectLeadingDash, _ = v.(bool)
+		case "result":
+			o.result, _ = v.(string)
+			o.result = strings.ToLower(o.result)
+			if err := validResult(o.result); err != nil {
+				return nil, fmt.Errorf("CMD ERROR: %w", err)
 			}
 		case strings.ToLower("maximumCmdTimeLive"):
 			var err error
@@ -73,9 +105,49 @@ func NewOrGet(r *reactor.Reactor, c map[string]any) (*Cmd, error) {
 				log.Print(err)
 				o.maximumCmdTimeLive = defaultMaximumCmdTimeLive
 			}
+		case "timeoutfrom":
+			o.timeoutFrom, _ = v.(string)
+			if !strings.HasPrefix(o.timeoutFrom, "$.") {
+				return nil, fmt.Errorf("CMD ERROR: invalid timeoutFrom %s, it must be a path like $.timeoutSeconds", o.timeoutFrom)
+			}
+		case "limits":
+			var err error
+			if o.limits, err = newLimits(v); err != nil {
+				return nil, fmt.Errorf("CMD ERROR: %w", err)
+			}
+			if err = validLimits(o.limits); err != nil {
+				return nil, fmt.Errorf("CMD ERROR: invalid limits: %w", err)
+			}
+		case "sandbox":
+			var err error
+			if o.sandbox, err = newSandbox(v); err != nil {
+				return nil, fmt.Errorf("CMD ERROR: invalid sandbox: %w", err)
+			}
+			if err = validSandbox(o.sandbox); err != nil {
+				return nil, fmt.Errorf("CMD ERROR: %w", err)
+			}
+		case "stopsignal":
+			o.stopSignalName, _ = v.(string)
+			o.stopSignalName = strings.ToUpper(o.stopSignalName)
+		case "stopgraceperiod":
+			var err error
+			if o.stopGracePeriod, err = time.ParseDuration(fmt.Sprint(v)); err != nil {
+				return nil, fmt.Errorf("CMD ERROR: invalid stopGracePeriod: %w", err)
+			}
 		}
 	}
 
+	var err error
+	if o.stopSignal, err = parseSignal(o.stopSignalName); err != nil {
+		return nil, fmt.Errorf("CMD ERROR: invalid stopSignal: %w", err)
+	}
+
+	// The file of the result is created out of the sandbox
+	if o.result == resultFile && o.sandbox != nil && (o.sandbox.privateTmp || o.sandbox.chroot != "") {
+		return nil, fmt.Errorf("CMD ERROR: result %s can't be used with the privateTmp or chroot of the sandbox, use %s or %s",
+			resultFile, resultFd, resultStdout)
+	}
+
 	if o.maximumCmdTimeLive == 0 {
 		o.maximumCmdTimeLive = defaultMaximumCmdTimeLive
 	}
@@ -85,16 +157,8 @@ func NewOrGet(r *reactor.Reactor, c map[string]any) (*Cmd, error) {
 // MatchConditions is a filter to replace the variables (usually commands arguments)
 // that are coming from the Input message
 func (o *Cmd) MatchConditions(msg lib.Msg) error {
-	for k, v := range o.cond {
-		if strings.HasPrefix(k, "$.") {
-			op, _ := jq.Parse(k[1:]) // create an Op
-			value, _ := op.Apply(msg.Body())
-			nv := bytes.Trim(value, "\"")
-
-			if !v.Match(nv) {
-				return reactor.ErrInvalidMsgForPlugin
-			}
-		}
+	if !o.cond.Match(msg) {
+		return reactor.ErrInvalidMsgForPlugin
 	}
 	return nil
 }
@@ -140,14 +204,7 @@ func (o *Cmd) findReplaceReturningSlice(msg lib.Msg, s string) []string {
 
 func (o *Cmd) replaceVariablesInArgs(msg lib.Msg, args []string) {
 	for i := range len(args) {
-		args[i] = strings.ReplaceAll(args[i], "${CreationTimestampMilliseconds}",
-			strconv.FormatInt(msg.CreationTimestampMilliseconds(), 10))
-
-		if strings.Contains(args[i], "${CreationTimestampSeconds}") {
-			var milliSecondsInSecond int64 = 1000
-			args[i] = strings.ReplaceAll(args[i], "${CreationTimestampSeconds}",
-				strconv.FormatInt(msg.CreationTimestampMilliseconds()/milliSecondsInSecond, 10))
-		}
+		args[i] = lib.ReplaceVariables(msg, args[i])
 	}
 }
 
@@ -164,6 +221,13 @@ func (o *Cmd) getReplacedArguments(msg lib.Msg) []string {
 // In this function we also define the OUT and ERR data destination of
 // the command.
 func (o *Cmd) Run(parentCtx context.Context, rl reactorlog.ReactorLog, msg lib.Msg) error {
+	_, err := o.RunResult(parentCtx, rl, msg)
+	return err
+}
+
+// RunResult executes the command like Run and returns its JSON result, nil if
+// the result is not defined or the command didn't return it
+func (o *Cmd) RunResult(parentCtx context.Context, rl reactorlog.ReactorLog, msg lib.Msg) ([]byte, error) {
 
 	args := o.getReplacedArguments(msg)
 
@@ -171,8 +235,34 @@ func (o *Cmd) Run(parentCtx context.Context, rl reactorlog.ReactorLog, msg lib.M
 	rl.SetLabel(logLabel)
 	rl.SetHash(msg.GetHash())
 
-	ctx, cancel := context.WithTimeout(parentCtx, o.maximumCmdTimeLive)
+	if err := o.validate(msg); err != nil {
+		log.Printf("Invalid message %s for %s: %s", msg.GetHash(), o.cmd, err)
+		rl.Write([]byte("invalid message: " + err.Error()))
+		return nil, reactor.ErrInvalidMsgForPlugin
+	}
+
+	var rc *resultCapture
+	if o.result != "" {
+		var err error
+		if rc, err = newResultCapture(o.result, o.user); err != nil {
+			rl.Write([]byte("error preparing the result: " + err.Error()))
+			return nil, err
+		}
+		defer rc.close()
+		args = rc.args(args)
+	}
+
+	if err := o.start(); err != nil {
+		return nil, err
+	}
+	defer o.running.Done()
+
+	timeout := o.timeout(rl, msg)
+	ctx, cancel := context.WithTimeout(parentCtx, timeout)
 	defer cancel()
+	lib.DeadlineFrom(parentCtx).Extend(time.Now().Add(timeout))
+	stopOnShutdown := context.AfterFunc(o.stopping, cancel)
+	defer stopOnShutdown()
 
 	var c *exec.Cmd
 	if len(args) > 0 {
@@ -184,31 +274,101 @@ func (o *Cmd) Run(parentCtx context.Context, rl reactorlog.ReactorLog, msg lib.M
 	if o.user != "" {
 		err := setUserToCmd(o.user, o.environment, c)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 	c.Dir = o.workingDirectory
+	stop := o.newStopper(ctx, parentCtx, c, rl)
 
-	c.Stdout = rl
-	c.Stderr = rl
+	stdout, stderr := streamWriters(rl)
+	c.Stdout = stdout
+	c.Stderr = stderr
+	if rc != nil {
+		rc.attach(c) // Before the child, that passes the extra files to the command
+	}
+
+	cg, st, err := o.prepareProcess(c)
+	if err != nil {
+		rl.Write([]byte("error preparing the process: " + err.Error()))
+		return nil, err
+	}
+	defer st.close()
+	defer func() {
+		if err := cg.close(); err != nil {
+			log.Printf("CMD %s: error removing the cgroup: %s", o.cmd, err)
+		}
+	}()
 
 	if err := c.Start(); err != nil {
 		rl.Write([]byte("error starting process " + o.cmd + " " + strings.Join(args, " ") + ": " + err.Error()))
-		return err
+		return nil, err
+	}
+	st.started()
+	if rc != nil {
+		rc.started()
 	}
 
 	pid := c.Process.Pid // Since Start returned correctly, c.Process is not null.
 	rl.Start(pid, o.cmd+" "+strings.Join(args, " "))
 
-	if err := c.Wait(); err != nil {
+	err = c.Wait()
+	timedOut := stop.finish() == stopReasonTimeout
+	if c.ProcessState != nil {
+		u := processUsage(c.ProcessState, timedOut, stdout, stderr)
+		u.Violations = cg.violations()
+		// The init of the PID namespace exits with 128+n when the command is
+		// terminated by a signal
+		if sig, ok := st.signal(); ok {
+			u.ExitCode = -1
+			u.Signal = sig.String()
+			err = fmt.Errorf("signal: %s", sig)
+		}
+		setUsage(rl, u)
+	}
+	if err != nil {
 		rl.Write([]byte("error running process: " + err.Error()))
-		return err
+		return nil, err
 	}
 
-	return nil
+	if rc == nil {
+		return nil, nil
+	}
+	result, err := rc.result()
+	if err != nil {
+		rl.Write([]byte("error reading the result: " + err.Error()))
+		return nil, err
+	}
+	return result, nil
 }
 
-// Exit will finish the command // TODO
-func (o *Cmd) Exit() {
+// timeout returns the timeout of the message from timeoutFrom, in seconds or
+// as a duration, capped by maximumCmdTimeLive
+func (o *Cmd) timeout(rl reactorlog.ReactorLog, msg lib.Msg) time.Duration {
+	if o.timeoutFrom == "" {
+		return o.maximumCmdTimeLive
+	}
 
+	v := string(lib.JSONPath(msg.Body(), o.timeoutFrom))
+	if v == "" || v == "null" {
+		return o.maximumCmdTimeLive
+	}
+	t, err := time.ParseDuration(v)
+	if sec, errSec := strconv.ParseFloat(v, 64); errSec == nil {
+		t, err = time.Duration(sec*float64(time.Second)), nil
+	}
+	if err != nil || t <= 0 {
+		rl.Write([]byte(fmt.Sprintf("invalid timeout %s in %s, using %s\n", v, o.timeoutFrom, o.maximumCmdTimeLive)))
+		return o.maximumCmdTimeLive
+	}
+	return min(t, o.maximumCmdTimeLive)
+}
+
+// Exit stops the running commands and waits until they finish, the new
+// executions are rejected
+func (o *Cmd) Exit() {
+	o.mu.Lock()
+	o.exiting = true
+	o.mu.Unlock()
+	o.shutdown()
+	o.running.Wait()
 }
@@ -2,9 +2,11 @@ package cmd
 
 import (
 	"testing"
+	"time"
 
 	"github.com/gabrielperezs/goreactor/lib"
 	"github.com/gabrielperezs/goreactor/reactor"
+	"github.com/gabrielperezs/goreactor/reactorlog/noopreactorlog"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -272,3 +274,177 @@ func TestFindReplaceWithArrayAndVariable(t *testing.T) {
 	assert.Equal(t, "--timestamp-with-milliseconds-precision", args[4])
 	assert.Equal(t, "1591784694", args[5])
 }
+
+func TestValidateArguments(t *testing.T) {
+	var r *reactor.Reactor = nil
+
+	c := make(map[string]any)
+	c["cmd"] = "cmd_name"
+	c["args"] = []any{"--id=$.id", "$.count", "$.stage", "$.files..."}
+	c["rejectLeadingDash"] = true
+	c["validate"] = []any{
+		map[string]any{"path": "$.id", "type": "uuid"},
+		map[string]any{"path": "$.count", "type": "int", "maxLength": int64(3)},
+		map[string]any{"path": "$.stage", "enum": []any{"stage", "prod"}},
+		map[string]any{"path": "$.files", "regex": "^[a-z0-9./]+$"},
+	}
+
+	cmd, err := NewOrGet(r, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	valid := `{"id":"0b35d38a-8270-45d0-a0d8-000000000000","count":"12","stage":"prod","files":["a.txt","b/c.txt"]}`
+	assert.NoError(t, cmd.validate(&Msg{B: []byte(valid)}))
+
+	invalid := []string{
+		`{"id":"--config=/etc/passwd","count":"12","stage":"prod","files":[]}`,
+		`{"id":"0b35d38a-8270-45d0-a0d8-000000000000","count":"1234","stage":"prod","files":[]}`,
+		`{"id":"0b35d38a-8270-45d0-a0d8-000000000000","count":"1a","stage":"prod","files":[]}`,
+		`{"id":"0b35d38a-8270-45d0-a0d8-000000000000","count":"12","stage":"dev","files":[]}`,
+		`{"id":"0b35d38a-8270-45d0-a0d8-000000000000","count":"12","stage":"prod","files":["a.txt","B.txt"]}`,
+	}
+	for _, b := range invalid {
+		assert.Error(t, cmd.validate(&Msg{B: []byte(b)}), b)
+	}
+}
+
+func TestValidateRejectLeadingDash(t *testing.T) {
+	var r *reactor.Reactor = nil
+
+	c := make(map[string]any)
+	c["cmd"] = "rm"
+	c["args"] = []any{"$.path", "$.more..."}
+	c["rejectLeadingDash"] = true
+
+	cmd, err := NewOrGet(r, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NoError(t, cmd.validate(&Msg{B: []byte(`{"path":"/tmp/a","more":["b"]}`)}))
+	assert.Error(t, cmd.validate(&Msg{B: []byte(`{"path":"-rf","more":["b"]}`)}))
+	assert.Error(t, cmd.validate(&Msg{B: []byte(`{"path":"/tmp/a","more":["b","--no-preserve-root"]}`)}))
+}
+
+func TestValidateVariables(t *testing.T) {
+	var r *reactor.Reactor = nil
+
+	c := make(map[string]any)
+	c["cmd"] = "rm"
+	c["args"] = []any{"--topic=${Envelope.TopicArn}", "${Envelope.Subject}", "$.path"}
+	c["rejectLeadingDash"] = true
+	c["validate"] = []any{
+		map[string]any{"path": "${Envelope.Subject}", "regex": "[a-z]+"},
+	}
+
+	cmd, err := NewOrGet(r, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := func(subject, topic string) *envelopeMsg {
+		return &envelopeMsg{
+			Msg:      Msg{B: []byte(`{"path":"/tmp/a"}`)},
+			envelope: map[string]string{"Subject": subject, "TopicArn": topic},
+		}
+	}
+
+	assert.NoError(t, cmd.validate(msg("launch", "--arn")))
+	assert.Error(t, cmd.validate(msg("--rf", "arn")))
+	assert.Error(t, cmd.validate(msg("launch1", "arn")))
+}
+
+type envelopeMsg struct {
+	Msg
+	envelope map[string]string
+}
+
+func (m *envelopeMsg) EnvelopeValue(name string) (string, bool) {
+	v, ok := m.envelope[name]
+	return v, ok
+}
+
+func TestEnvelopeVariablesAndConditions(t *testing.T) {
+	var r *reactor.Reactor = nil
+
+	c := make(map[string]any)
+	c["cmd"] = "cmd_name"
+	c["args"] = []any{"--topic=${Envelope.TopicArn}", "${Envelope.MessageAttributes.stage}", "$.id"}
+	c["cond"] = []any{map[string]any{"${Envelope.Subject}": "^launch$"}}
+
+	cmd, err := NewOrGet(r, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := &envelopeMsg{
+		Msg: Msg{B: []byte(`{"id":"i-1"}`)},
+		envelope: map[string]string{
+			"TopicArn":                "arn:aws:sns:eu-west-1:9999999999:events",
+			"Subject":                 "launch",
+			"MessageAttributes.stage": "prod",
+		},
+	}
+
+	assert.NoError(t, cmd.MatchConditions(msg))
+	assert.Equal(t, []string{"--topic=arn:aws:sns:eu-west-1:9999999999:events", "prod", "i-1"}, cmd.getReplacedArguments(msg))
+
+	msg.envelope["Subject"] = "terminate"
+	assert.Equal(t, reactor.ErrInvalidMsgForPlugin, cmd.MatchConditions(msg))
+	assert.Equal(t, reactor.ErrInvalidMsgForPlugin, cmd.MatchConditions(&Msg{B: []byte(`{"id":"i-1"}`)}))
+}
+
+type variablesMsg struct {
+	Msg
+	variables map[string]string
+}
+
+func (m *variablesMsg) Variable(name string) (string, bool) {
+	v, ok := m.variables[name]
+	return v, ok
+}
+
+func TestFindReplaceMsgVariables(t *testing.T) {
+	var r *reactor.Reactor = nil
+
+	c := make(map[string]any)
+	c["cmd"] = "cmd_name"
+	c["args"] = []any{"s3://${S3Bucket}/${S3Key}", "${S3Size}", "${Unknown}"}
+
+	cmd, err := NewOrGet(r, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := &variablesMsg{
+		variables: map[string]string{"S3Bucket": "bucket", "S3Key": "dir/file.txt", "S3Size": "10"},
+	}
+
+	assert.Equal(t, []string{"s3://bucket/dir/file.txt", "10", "${Unknown}"}, cmd.getReplacedArguments(msg))
+}
+
+func TestTimeoutFrom(t *testing.T) {
+	cmd, err := NewOrGet(nil, map[string]any{
+		"cmd":                "cmd_name",
+		"timeoutFrom":        "$.timeoutSeconds",
+		"maximumCmdTimeLive": "1h",
+	})
+	assert.NoError(t, err)
+
+	rl := noopreactorlog.NoopReactorLog{}
+	for body, timeout := range map[string]time.Duration{
+		`{"timeoutSeconds":90}`:    90 * time.Second,
+		`{"timeoutSeconds":"2.5"}`: 2500 * time.Millisecond,
+		`{"timeoutSeconds":"20m"}`: 20 * time.Minute,
+		`{"timeoutSeconds":86400}`: time.Hour,
+		`{"timeoutSeconds":-1}`:    time.Hour,
+		`{"timeoutSeconds":"no"}`:  time.Hour,
+		`{}`:                       time.Hour,
+	} {
+		assert.Equal(t, timeout, cmd.timeout(rl, &Msg{B: []byte(body)}), body)
+	}
+
+	_, err = NewOrGet(nil, map[string]any{"cmd": "cmd_name", "timeoutFrom": "timeoutSeconds"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// limits are the resources that a command can use, the values not defined
+// are -1
+type limits struct {
+	memory    int64   // Bytes
+	cpu       float64 // CPUs, 0 without limit
+	pids      int64
+	openFiles int64
+	core      int64  // Bytes
+	cgroup    string // Parent cgroup v2 of the executions
+}
+
+func (l *limits) empty() bool {
+	return l == nil || (l.memory < 0 && l.cpu == 0 && l.pids < 0 && l.openFiles < 0 && l.core < 0)
+}
+
+// newLimits reads the table of limits of the command
+func newLimits(v any) (*limits, error) {
+	cfg, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid limits %v", v)
+	}
+
+	l := &limits{memory: -1, pids: -1, openFiles: -1, core: -1}
+	for k, v := range cfg {
+		var err error
+		switch strings.ToLower(k) {
+		case "memory":
+			l.memory, err = parseSize(v)
+		case "cpu":
+			l.cpu, err = strconv.ParseFloat(fmt.Sprint(v), 64)
+			if err == nil && l.cpu <= 0 {
+				err = fmt.Errorf("it must be greater than 0")
+			}
+		case "pids":
+			l.pids, err = parseCount(v)
+		case "openfiles":
+			l.openFiles, err = parseCount(v)
+		case "core":
+			l.core, err = parseSize(v)
+		case "cgroup":
+			l.cgroup, _ = v.(string)
+		default:
+			err = fmt.Errorf("unknown limit")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid limit %s %v: %w", k, v, err)
+		}
+	}
+	return l, nil
+}
+
+func parseCount(v any) (int64, error) {
+	n, err := strconv.ParseInt(fmt.Sprint(v), 10, 64)
+	if err == nil && n < 0 {
+		err = fmt.Errorf("it can't be negative")
+	}
+	return n, err
+}
+
+// parseSize reads bytes, or a size with the K, M, G or T suffix (powers of 1024)
+func parseSize(v any) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(fmt.Sprint(v)))
+	s = strings.TrimSuffix(strings.TrimSuffix(s, "B"), "I")
+
+	mult := int64(1)
+	if s != "" {
+		switch s[len(s)-1] {
+		case 'K':
+			mult = 1 << 10
+		case 'M':
+			mult = 1 << 20
+		case 'G':
+			mult = 1 << 30
+		case 'T':
+			mult = 1 << 40
+		}
+		if mult > 1 {
+			s = s[:len(s)-1]
+		}
+	}
+
+	n, err := parseCount(strings.TrimSpace(s))
+	if err != nil {
+		return 0, err
+	}
+	return n * mult, nil
+}
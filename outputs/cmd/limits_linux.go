@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+const (
+	cpuPeriod = 100000 // Microseconds of cpu.max
+)
+
+var cgroupSeq atomic.Uint64
+
+func validLimits(l *limits) error {
+	if l.cpu > 0 && l.cgroup == "" {
+		return fmt.Errorf("cpu requires cgroup")
+	}
+	// RLIMIT_NPROC counts all the processes of the user and it's not
+	// enforced for root, it's not a limit of the execution
+	if l.pids >= 0 && l.cgroup == "" {
+		return fmt.Errorf("pids requires cgroup")
+	}
+	return nil
+}
+
+// rlimits returns the limits applied with setrlimit, memory is only applied
+// if there isn't cgroup, they are not exactly the same
+func (l *limits) rlimits(withCgroup bool) []childRlimit {
+	var r []childRlimit
+	add := func(resource int, v int64) {
+		if v >= 0 {
+			r = append(r, childRlimit{Resource: resource, Value: uint64(v)})
+		}
+	}
+	add(syscall.RLIMIT_NOFILE, l.openFiles)
+	add(syscall.RLIMIT_CORE, l.core)
+	if !withCgroup {
+		add(syscall.RLIMIT_AS, l.memory)
+	}
+	return r
+}
+
+// applyLimits adds the limits to the command and the spec of the child,
+// it returns the cgroup of the execution if it's defined
+func (o *Cmd) applyLimits(c *exec.Cmd, spec *childSpec) (*cgroup, error) {
+	if o.limits.empty() {
+		return nil, nil
+	}
+
+	var cg *cgroup
+	if o.limits.cgroup != "" {
+		var err error
+		if cg, err = newCgroup(o.limits.cgroup, o.limits); err != nil {
+			return nil, err
+		}
+		if c.SysProcAttr == nil {
+			c.SysProcAttr = &syscall.SysProcAttr{}
+		}
+		c.SysProcAttr.UseCgroupFD = true
+		c.SysProcAttr.CgroupFD = int(cg.fd.Fd())
+	}
+	spec.Rlimits = o.limits.rlimits(cg != nil)
+	return cg, nil
+}
+
+// cgroup is the cgroup v2 of one execution, all the processes of the
+// execution are in the cgroup
+type cgroup struct {
+	path string
+	fd   *os.File
+}
+
+func newCgroup(parent string, l *limits) (*cgroup, error) {
+	if _, err := os.Stat(filepath.Join(parent, "cgroup.controllers")); err != nil {
+		return nil, fmt.Errorf("%s is not a cgroup v2: %w", parent, err)
+	}
+
+	// The controllers could be already enabled in the parent
+	for _, controller := range []string{"memory", "cpu", "pids"} {
+		os.WriteFile(filepath.Join(parent, "cgroup.subtree_control"), []byte("+"+controller), 0644)
+	}
+
+	cg := &cgroup{
+		path: filepath.Join(parent, fmt.Sprintf("goreactor-%d-%d", os.Getpid(), cgroupSeq.Add(1))),
+	}
+	if err := os.Mkdir(cg.path, 0755); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if l.memory >= 0 {
+		err = errors.Join(err, cg.write("memory.max", strconv.FormatInt(l.memory, 10)))
+	}
+	if l.cpu > 0 {
+		err = errors.Join(err, cg.write("cpu.max", fmt.Sprintf("%d %d", int64(l.cpu*cpuPeriod), cpuPeriod)))
+	}
+	if l.pids >= 0 {
+		err = errors.Join(err, cg.write("pids.max", strconv.FormatInt(l.pids, 10)))
+	}
+	if err == nil {
+		cg.fd, err = os.Open(cg.path)
+	}
+	if err != nil {
+		cg.close()
+		return nil, err
+	}
+	return cg, nil
+}
+
+func (cg *cgroup) write(name, value string) error {
+	return os.WriteFile(filepath.Join(cg.path, name), []byte(value), 0644)
+}
+
+// violations returns the limits that were reached
+func (cg *cgroup) violations() []string {
+	if cg == nil {
+		return nil
+	}
+	var v []string
+	if cg.event("memory.events", "oom_kill") > 0 {
+		v = append(v, "memory")
+	}
+	if cg.event("pids.events", "max") > 0 {
+		v = append(v, "pids")
+	}
+	return v
+}
+
+// event returns the counter of the events file
+func (cg *cgroup) event(file, name string) int64 {
+	f, err := os.Open(filepath.Join(cg.path, file))
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) == 2 && fields[0] == name {
+			n, _ := strconv.ParseInt(fields[1], 10, 64)
+			return n
+		}
+	}
+	return 0
+}
+
+// close kills the processes that are still in the cgroup and removes it
+func (cg *cgroup) close() error {
+	if cg == nil {
+		return nil
+	}
+	if cg.fd != nil {
+		cg.fd.Close()
+	}
+	cg.write("cgroup.kill", "1")
+
+	// The killed processes leave the cgroup asynchronously
+	var err error
+	for range 50 {
+		if err = os.Remove(cg.path); err == nil || os.IsNotExist(err) {
+			return nil
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	return err
+}
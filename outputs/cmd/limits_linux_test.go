@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRlimits(t *testing.T) {
+	o := newStopCmd(t, "ulimit -n; ulimit -c; ulimit -v", map[string]any{
+		"limits": map[string]any{"openFiles": 64, "core": 0, "memory": "1G"},
+	})
+
+	l := &linesLog{}
+	err := o.Run(context.Background(), l, &Msg{B: []byte(`{}`)})
+	assert.NoError(t, err)
+	assert.Equal(t, "64\n0\n1048576\n", l.lines.String())
+	assert.Empty(t, l.usage.Violations)
+}
+
+func TestRlimitsWithCgroup(t *testing.T) {
+	l := &limits{memory: 1 << 30, pids: 10, openFiles: 64, core: -1}
+	assert.Equal(t, []childRlimit{{Resource: syscall.RLIMIT_NOFILE, Value: 64}}, l.rlimits(true))
+	assert.Equal(t, []childRlimit{
+		{Resource: syscall.RLIMIT_NOFILE, Value: 64},
+		{Resource: syscall.RLIMIT_AS, Value: 1 << 30},
+	}, l.rlimits(false))
+
+	_, err := NewOrGet(nil, map[string]any{"cmd": "true", "limits": map[string]any{"cpu": 1}})
+	assert.Error(t, err)
+	_, err = NewOrGet(nil, map[string]any{"cmd": "true", "limits": map[string]any{"pids": 10}})
+	assert.ErrorContains(t, err, "pids requires cgroup")
+}
+
+func TestCgroup(t *testing.T) {
+	parent := t.TempDir()
+	_, err := newCgroup(parent, &limits{memory: 1 << 20})
+	assert.Error(t, err, "not a cgroup v2")
+
+	// A directory with the files of a cgroup v2
+	assert.NoError(t, os.WriteFile(filepath.Join(parent, "cgroup.controllers"), []byte("cpu memory pids\n"), 0644))
+	cg, err := newCgroup(parent, &limits{memory: 1 << 20, cpu: 1.5, pids: 20, openFiles: -1, core: -1})
+	assert.NoError(t, err)
+	defer cg.fd.Close()
+
+	read := func(name string) string {
+		b, _ := os.ReadFile(filepath.Join(cg.path, name))
+		return string(b)
+	}
+	assert.Equal(t, "1048576", read("memory.max"))
+	assert.Equal(t, "150000 100000", read("cpu.max"))
+	assert.Equal(t, "20", read("pids.max"))
+	assert.Empty(t, cg.violations())
+
+	cg.write("memory.events", "low 0\nhigh 0\nmax 3\noom 1\noom_kill 1\n")
+	cg.write("pids.events", "max 2\n")
+	assert.Equal(t, []string{"memory", "pids"}, cg.violations())
+}
@@ -0,0 +1,38 @@
+//go:build !linux
+
+package cmd
+
+import (
+	"fmt"
+	"os/exec"
+	"syscall"
+)
+
+func validLimits(l *limits) error {
+	return fmt.Errorf("limits are only supported on linux")
+}
+
+type cgroup struct{}
+
+func (o *Cmd) prepareProcess(c *exec.Cmd) (*cgroup, *initStatus, error) {
+	return nil, nil, nil
+}
+
+// initStatus only exists with the PID namespace of linux
+type initStatus struct{}
+
+func (st *initStatus) started() {}
+
+func (st *initStatus) signal() (syscall.Signal, bool) {
+	return 0, false
+}
+
+func (st *initStatus) close() {}
+
+func (cg *cgroup) violations() []string {
+	return nil
+}
+
+func (cg *cgroup) close() error {
+	return nil
+}
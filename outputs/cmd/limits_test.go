@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewLimits(t *testing.T) {
+	l, err := newLimits(map[string]any{
+		"memory":    "512M",
+		"cpu":       0.5,
+		"pids":      int64(100),
+		"openFiles": 1024,
+		"core":      "0",
+		"cgroup":    "/sys/fs/cgroup/goreactor",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, &limits{
+		memory:    512 << 20,
+		cpu:       0.5,
+		pids:      100,
+		openFiles: 1024,
+		core:      0,
+		cgroup:    "/sys/fs/cgroup/goreactor",
+	}, l)
+	assert.False(t, l.empty())
+
+	l, err = newLimits(map[string]any{"cgroup": "/sys/fs/cgroup/goreactor"})
+	assert.NoError(t, err)
+	assert.True(t, l.empty())
+
+	for _, cfg := range []map[string]any{
+		{"memory": "lots"},
+		{"memory": "-1G"},
+		{"cpu": 0},
+		{"pids": "many"},
+		{"swap": "1G"},
+	} {
+		_, err := newLimits(cfg)
+		assert.Error(t, err, "%v", cfg)
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	for s, n := range map[any]int64{
+		int64(100): 100,
+		"2k":       2 << 10,
+		"1.5":      -1,
+		"3MiB":     3 << 20,
+		"1G":       1 << 30,
+		"1 TB":     1 << 40,
+	} {
+		v, err := parseSize(s)
+		if n < 0 {
+			assert.Error(t, err, "%v", s)
+			continue
+		}
+		assert.NoError(t, err, "%v", s)
+		assert.Equal(t, n, v, "%v", s)
+	}
+}
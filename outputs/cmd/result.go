@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+const (
+	resultStdout = "stdout" // The last line of the stdout
+	resultFile   = "file"   // The file ${ResultFile}
+	resultFd     = "fd"     // The file descriptor 3
+
+	resultFileVariable = "${ResultFile}"
+	maxResultSize      = 1 << 20
+)
+
+func validResult(mode string) error {
+	switch mode {
+	case "", resultStdout, resultFile, resultFd:
+		return nil
+	}
+	return fmt.Errorf("invalid result %s, valid values are %s, %s or %s", mode, resultStdout, resultFile, resultFd)
+}
+
+// lastLine is a writer that keeps the last line that is not empty
+type lastLine struct {
+	w            io.Writer
+	current      []byte
+	last         []byte
+	overflow     bool // The current line is larger than maxResultSize
+	lastOverflow bool // The last line is larger than maxResultSize
+}
+
+func (l *lastLine) Write(b []byte) (int, error) {
+	for _, c := range b {
+		if c == '\n' {
+			l.endLine()
+			continue
+		}
+		if len(l.current) >= maxResultSize {
+			l.overflow = true
+			continue
+		}
+		l.current = append(l.current, c)
+	}
+	return l.w.Write(b)
+}
+
+func (l *lastLine) endLine() {
+	if l.overflow || len(bytes.TrimSpace(l.current)) > 0 {
+		l.last = append(l.last[:0], l.current...)
+		l.lastOverflow = l.overflow
+	}
+	l.current = l.current[:0]
+	l.overflow = false
+}
+
+// resultCapture collects the result of one execution of the command
+type resultCapture struct {
+	mode   string
+	stdout *lastLine
+	file   string
+	r, w   *os.File
+	buf    bytes.Buffer
+	read   chan error
+}
+
+func newResultCapture(mode, user string) (*resultCapture, error) {
+	rc := &resultCapture{mode: mode}
+
+	switch mode {
+	case resultFile:
+		f, err := os.CreateTemp("", "goreactor-result-")
+		if err != nil {
+			return nil, err
+		}
+		rc.file = f.Name()
+		f.Close()
+		if user != "" {
+			if err := chownToUser(rc.file, user); err != nil {
+				os.Remove(rc.file)
+				return nil, err
+			}
+		}
+	case resultFd:
+		var err error
+		if rc.r, rc.w, err = os.Pipe(); err != nil {
+			return nil, err
+		}
+	}
+	return rc, nil
+}
+
+// args replaces ${ResultFile} in the arguments of the command
+func (rc *resultCapture) args(args []string) []string {
+	if rc.mode != resultFile {
+		return args
+	}
+	for i := range args {
+		args[i] = strings.ReplaceAll(args[i], resultFileVariable, rc.file)
+	}
+	return args
+}
+
+// attach connects the command to the result
+func (rc *resultCapture) attach(c *exec.Cmd) {
+	switch rc.mode {
+	case resultStdout:
+		rc.stdout = &lastLine{w: c.Stdout}
+		c.Stdout = rc.stdout
+	case resultFd:
+		c.ExtraFiles = []*os.File{rc.w}
+	}
+}
+
+// started is called after the start of the command, the result in the file
+// descriptor is read while the command runs
+func (rc *resultCapture) started() {
+	if rc.mode != resultFd {
+		return
+	}
+	rc.w.Close()
+	rc.w = nil
+	rc.read = make(chan error, 1)
+	go func() {
+		_, err := io.Copy(&rc.buf, io.LimitReader(rc.r, maxResultSize+1))
+		io.Copy(io.Discard, rc.r)
+		rc.read <- err
+	}()
+}
+
+// result returns the JSON result, or nil if the command didn't return it
+func (rc *resultCapture) result() ([]byte, error) {
+	var b []byte
+	switch rc.mode {
+	case resultStdout:
+		rc.stdout.endLine()
+		if rc.stdout.lastOverflow {
+			return nil, fmt.Errorf("result larger than %d bytes", maxResultSize)
+		}
+		b = rc.stdout.last
+	case resultFile:
+		f, err := os.Open(rc.file)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		if b, err = io.ReadAll(io.LimitReader(f, maxResultSize+1)); err != nil {
+			return nil, err
+		}
+	case resultFd:
+		if err := <-rc.read; err != nil {
+			return nil, err
+		}
+		b = rc.buf.Bytes()
+	}
+
+	if len(b) > maxResultSize {
+		return nil, fmt.Errorf("result larger than %d bytes", maxResultSize)
+	}
+	b = bytes.TrimSpace(b)
+	if len(b) == 0 {
+		return nil, nil
+	}
+	if !json.Valid(b) {
+		return nil, fmt.Errorf("the result is not valid JSON")
+	}
+	return b, nil
+}
+
+// close removes the file and the pipe of the result
+func (rc *resultCapture) close() {
+	if rc.file != "" {
+		os.Remove(rc.file)
+	}
+	if rc.w != nil {
+		rc.w.Close()
+	}
+	if rc.r != nil {
+		rc.r.Close()
+	}
+}
@@ -0,0 +1,74 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gabrielperezs/goreactor/reactor"
+	"github.com/gabrielperezs/goreactor/reactorlog/noopreactorlog"
+	"github.com/stretchr/testify/assert"
+)
+
+func runResult(t *testing.T, result string, script string) ([]byte, error) {
+	t.Helper()
+	c := map[string]any{
+		"cmd":    "/bin/sh",
+		"args":   []any{"-c", script, "sh", "$.id", "${ResultFile}"},
+		"result": result,
+	}
+	o, err := NewOrGet(reactor.NewReactor(map[string]any{}), c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return o.RunResult(context.Background(), noopreactorlog.NoopReactorLog{}, &Msg{B: []byte(`{"id":"a1"}`)})
+}
+
+func TestResultStdout(t *testing.T) {
+	b, err := runResult(t, "stdout", `echo working; echo "{\"id\":\"$1\"}"; echo`)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"id":"a1"}`, string(b))
+
+	b, err = runResult(t, "stdout", `echo not json`)
+	assert.Error(t, err)
+	assert.Nil(t, b)
+}
+
+func TestResultStdoutLargeLog(t *testing.T) {
+	// A log line over the limit before the result
+	b, err := runResult(t, "stdout", `head -c 1100000 /dev/zero | tr '\0' x; echo; echo "{\"id\":\"$1\"}"`)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"id":"a1"}`, string(b))
+
+	_, err = runResult(t, "stdout", `echo "{\"id\":\"$1\"}"; head -c 1100000 /dev/zero | tr '\0' x; echo`)
+	assert.ErrorContains(t, err, "result larger than")
+}
+
+func TestResultFile(t *testing.T) {
+	b, err := runResult(t, "file", `echo working; echo "{\"id\":\"$1\"}" > "$2"`)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"id":"a1"}`, string(b))
+
+	b, err = runResult(t, "file", `echo nothing`)
+	assert.NoError(t, err)
+	assert.Nil(t, b, "Without result")
+}
+
+func TestResultFd(t *testing.T) {
+	b, err := runResult(t, "fd", `echo working; echo "{\"id\":\"$1\"}" >&3`)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"id":"a1"}`, string(b))
+}
+
+func TestResultInvalidSource(t *testing.T) {
+	_, err := NewOrGet(nil, map[string]any{"cmd": "/bin/true", "result": "stderr"})
+	assert.Error(t, err)
+}
+
+func TestResultFileSandbox(t *testing.T) {
+	for _, s := range []map[string]any{{"privateTmp": true}, {"chroot": "/srv/sandbox"}} {
+		_, err := NewOrGet(nil, map[string]any{"cmd": "/bin/true", "result": "file", "sandbox": s})
+		assert.Error(t, err, "%v", s)
+	}
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+const (
+	namespaceMount = "mount"
+	namespacePid   = "pid"
+	namespaceNet   = "net"
+	namespaceIpc   = "ipc"
+)
+
+// sandbox isolates the processes of the command in their own namespaces
+type sandbox struct {
+	namespaces []string
+	readOnly   []string // Paths mounted read-only
+	privateTmp bool     // An empty /tmp for every execution
+	chroot     string
+}
+
+// newSandbox reads the table of the sandbox, by default the processes have
+// all the namespaces
+func newSandbox(v any) (*sandbox, error) {
+	cfg, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid sandbox %v", v)
+	}
+
+	s := &sandbox{
+		namespaces: []string{namespaceMount, namespacePid, namespaceNet, namespaceIpc},
+	}
+	for k, v := range cfg {
+		switch strings.ToLower(k) {
+		case "namespaces":
+			s.namespaces = nil
+			for _, n := range v.([]any) {
+				ns := strings.ToLower(fmt.Sprint(n))
+				switch ns {
+				case namespaceMount, namespacePid, namespaceNet, namespaceIpc:
+				default:
+					return nil, fmt.Errorf("invalid namespace %s", ns)
+				}
+				s.namespaces = append(s.namespaces, ns)
+			}
+		case "readonly":
+			for _, n := range v.([]any) {
+				p := fmt.Sprint(n)
+				if !filepath.IsAbs(p) {
+					return nil, fmt.Errorf("readOnly path %s is not absolute", p)
+				}
+				s.readOnly = append(s.readOnly, filepath.Clean(p))
+			}
+		case "privatetmp":
+			s.privateTmp, _ = v.(bool)
+		case "chroot":
+			s.chroot, _ = v.(string)
+			if !filepath.IsAbs(s.chroot) {
+				return nil, fmt.Errorf("chroot %s is not absolute", s.chroot)
+			}
+		default:
+			return nil, fmt.Errorf("unknown sandbox setting %s", k)
+		}
+	}
+
+	if (len(s.readOnly) > 0 || s.privateTmp) && !s.has(namespaceMount) {
+		return nil, fmt.Errorf("readOnly and privateTmp require the mount namespace")
+	}
+	return s, nil
+}
+
+func (s *sandbox) has(namespace string) bool {
+	return slices.Contains(s.namespaces, namespace)
+}
@@ -0,0 +1,231 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+var namespaceFlags = map[string]uintptr{
+	namespaceMount: syscall.CLONE_NEWNS,
+	namespacePid:   syscall.CLONE_NEWPID,
+	namespaceNet:   syscall.CLONE_NEWNET,
+	namespaceIpc:   syscall.CLONE_NEWIPC,
+}
+
+// validSandbox checks that goreactor can create the namespaces and the
+// mounts of the sandbox
+func validSandbox(s *sandbox) error {
+	if os.Geteuid() != 0 {
+		return fmt.Errorf("sandbox requires running goreactor as root")
+	}
+	return nil
+}
+
+// childSandbox is the part of the sandbox that is prepared by the child,
+// in the new namespaces before executing the command
+type childSandbox struct {
+	ReadOnly   []string            `json:",omitempty"`
+	PrivateTmp bool                `json:",omitempty"`
+	Proc       bool                `json:",omitempty"` // Mount /proc of the PID namespace
+	Init       bool                `json:",omitempty"` // The child is the init of the PID namespace
+	Chroot     string              `json:",omitempty"`
+	Dir        string              `json:",omitempty"`
+	Credential *syscall.Credential `json:",omitempty"` // Dropped after preparing the sandbox
+}
+
+// apply starts the command in the namespaces of the sandbox, the child
+// prepares the mounts and drops the privileges of root
+func (s *sandbox) apply(c *exec.Cmd, spec *childSpec) {
+	if s == nil {
+		return
+	}
+	if c.SysProcAttr == nil {
+		c.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	for _, ns := range s.namespaces {
+		c.SysProcAttr.Cloneflags |= namespaceFlags[ns]
+	}
+
+	spec.Sandbox = &childSandbox{
+		ReadOnly:   s.readOnly,
+		PrivateTmp: s.privateTmp,
+		Proc:       s.has(namespaceMount) && s.has(namespacePid),
+		Init:       s.has(namespacePid),
+		Chroot:     s.chroot,
+		Dir:        c.Dir,
+		Credential: c.SysProcAttr.Credential,
+	}
+	c.Dir = ""
+	c.SysProcAttr.Credential = nil
+
+	if s.chroot != "" {
+		// The command is searched in the chroot
+		c.Path = c.Args[0]
+		c.Err = nil
+	}
+}
+
+// setup prepares the sandbox in the child
+func (s *childSandbox) setup() error {
+	root := "/"
+	if s.Chroot != "" {
+		root = s.Chroot
+	}
+
+	if len(s.ReadOnly) > 0 || s.PrivateTmp || s.Proc {
+		// The mounts are not propagated out of the namespace
+		if err := syscall.Mount("none", "/", "", syscall.MS_REC|syscall.MS_PRIVATE, ""); err != nil {
+			return fmt.Errorf("private mounts: %w", err)
+		}
+	}
+
+	for _, p := range s.ReadOnly {
+		target := filepath.Join(root, p)
+		if err := mountPoint(p, target); err != nil {
+			return fmt.Errorf("readOnly %s: %w", p, err)
+		}
+		if err := syscall.Mount(p, target, "", syscall.MS_BIND|syscall.MS_REC, ""); err != nil {
+			return fmt.Errorf("readOnly %s: %w", p, err)
+		}
+		// The remount is not recursive, every mount under the path is
+		// remounted read-only
+		mounts, err := subMounts(target)
+		if err != nil {
+			return fmt.Errorf("readOnly %s: %w", p, err)
+		}
+		flags := uintptr(syscall.MS_BIND | syscall.MS_REMOUNT | syscall.MS_RDONLY | syscall.MS_NOSUID)
+		for _, m := range mounts {
+			if err := syscall.Mount("", m, "", flags, ""); err != nil {
+				return fmt.Errorf("readOnly %s: %w", m, err)
+			}
+		}
+	}
+
+	if s.PrivateTmp {
+		target := filepath.Join(root, "tmp")
+		if err := syscall.Mount("tmpfs", target, "tmpfs", syscall.MS_NOSUID|syscall.MS_NODEV, "mode=1777"); err != nil {
+			return fmt.Errorf("privateTmp: %w", err)
+		}
+	}
+
+	if s.Proc {
+		target := filepath.Join(root, "proc")
+		if _, err := os.Stat(target); err == nil {
+			flags := uintptr(syscall.MS_NOSUID | syscall.MS_NODEV | syscall.MS_NOEXEC)
+			if err := syscall.Mount("proc", target, "proc", flags, ""); err != nil {
+				return fmt.Errorf("proc: %w", err)
+			}
+		}
+	}
+
+	if s.Chroot != "" {
+		if err := syscall.Chroot(s.Chroot); err != nil {
+			return fmt.Errorf("chroot: %w", err)
+		}
+	}
+	dir := s.Dir
+	if dir == "" && s.Chroot != "" {
+		dir = "/"
+	}
+	if dir != "" {
+		if err := syscall.Chdir(dir); err != nil {
+			return fmt.Errorf("chdir %s: %w", dir, err)
+		}
+	}
+	return nil
+}
+
+// subMounts returns the mount points of the path and under it
+func subMounts(path string) ([]string, error) {
+	b, err := os.ReadFile("/proc/self/mountinfo")
+	if err != nil {
+		return nil, err
+	}
+
+	mounts := []string{path}
+	prefix := strings.TrimSuffix(path, "/") + "/"
+	for _, line := range strings.Split(string(b), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 5 {
+			continue
+		}
+		m := unescapeMountInfo(fields[4])
+		if strings.HasPrefix(m, prefix) && !slices.Contains(mounts, m) {
+			mounts = append(mounts, m)
+		}
+	}
+	return mounts, nil
+}
+
+// unescapeMountInfo replaces the octal escapes of the mountinfo, like \040
+// for the space
+func unescapeMountInfo(s string) string {
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) {
+			if n, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// mountPoint creates the target of the bind mount in the chroot, like the
+// source
+func mountPoint(source, target string) error {
+	if source == target {
+		return nil
+	}
+	fi, err := os.Stat(source)
+	if err != nil {
+		return err
+	}
+	if fi.IsDir() {
+		return os.MkdirAll(target, 0755)
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(target, os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// dropCredential runs the process as the user of the command
+func (s *childSandbox) dropCredential() error {
+	cred := s.Credential
+	if cred == nil {
+		return nil
+	}
+	if !cred.NoSetGroups {
+		groups := make([]int, len(cred.Groups))
+		for i, g := range cred.Groups {
+			groups[i] = int(g)
+		}
+		if err := syscall.Setgroups(groups); err != nil {
+			return fmt.Errorf("setgroups: %w", err)
+		}
+	}
+	if err := syscall.Setgid(int(cred.Gid)); err != nil {
+		return fmt.Errorf("setgid: %w", err)
+	}
+	if err := syscall.Setuid(int(cred.Uid)); err != nil {
+		return fmt.Errorf("setuid: %w", err)
+	}
+	return nil
+}
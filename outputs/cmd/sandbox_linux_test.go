@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// skipWithoutNamespaces skips the tests of the sandbox when they can't
+// create namespaces, they require root
+func skipWithoutNamespaces(t *testing.T) {
+	t.Helper()
+	if os.Getuid() != 0 {
+		t.Skip("the sandbox requires root")
+	}
+	if err := exec.Command("unshare", "-m", "-p", "-n", "-i", "-f", "true").Run(); err != nil {
+		t.Skipf("namespaces are not available: %s", err)
+	}
+}
+
+func runSandbox(t *testing.T, script string, extra map[string]any) (string, error) {
+	t.Helper()
+	o := newStopCmd(t, script, extra)
+	l := &linesLog{}
+	err := o.Run(context.Background(), l, &Msg{B: []byte(`{}`)})
+	return l.lines.String(), err
+}
+
+func TestNewSandbox(t *testing.T) {
+	s, err := newSandbox(map[string]any{"readOnly": []any{"/usr/"}, "privateTmp": true})
+	assert.NoError(t, err)
+	assert.Equal(t, &sandbox{
+		namespaces: []string{namespaceMount, namespacePid, namespaceNet, namespaceIpc},
+		readOnly:   []string{"/usr"},
+		privateTmp: true,
+	}, s)
+
+	for _, cfg := range []map[string]any{
+		{"namespaces": []any{"user"}},
+		{"namespaces": []any{"net"}, "privateTmp": true},
+		{"readOnly": []any{"usr"}},
+		{"chroot": "jail"},
+		{"seccomp": true},
+	} {
+		_, err := newSandbox(cfg)
+		assert.Error(t, err, "%v", cfg)
+	}
+}
+
+func TestSandboxNamespaces(t *testing.T) {
+	skipWithoutNamespaces(t)
+
+	// Not in /tmp, that is replaced by the private /tmp
+	dir, err := os.MkdirTemp("/var/tmp", "goreactor-sandbox-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	out, err := runSandbox(t, `echo $PPID; grep -c : /proc/net/dev; touch /tmp/private; ls /tmp; touch `+dir+`/file`, map[string]any{
+		"sandbox": map[string]any{"readOnly": []any{dir}, "privateTmp": true},
+	})
+	assert.Error(t, err)
+	lines := strings.Split(out, "\n")
+	assert.Equal(t, "1", lines[0], "PID namespace, the parent is the init")
+	assert.Equal(t, "1", lines[1], "only the loopback in the net namespace")
+	assert.Equal(t, "private", lines[2], "private /tmp")
+	assert.Contains(t, lines[3], "Read-only file system")
+	assert.NoFileExists(t, "/tmp/private")
+}
+
+func TestSandboxStopInit(t *testing.T) {
+	skipWithoutNamespaces(t)
+
+	// The init forwards the signal to the command and reaps the orphan
+	st := time.Now()
+	out, err := runSandbox(t, `sleep 30 & sleep 30`, map[string]any{
+		"sandbox":            map[string]any{"namespaces": []any{"pid"}},
+		"maximumCmdTimeLive": "200ms",
+		"stopGracePeriod":    "10s",
+	})
+	assert.Error(t, err)
+	assert.Less(t, time.Since(st), 5*time.Second, out)
+}
+
+func TestSandboxInitSignal(t *testing.T) {
+	skipWithoutNamespaces(t)
+
+	// The signal of the command reaches the END line, not the exit code of the init
+	o := newStopCmd(t, `kill -TERM $$`, map[string]any{"sandbox": map[string]any{"namespaces": []any{"pid"}}})
+	l := &linesLog{}
+	err := o.Run(context.Background(), l, &Msg{B: []byte(`{}`)})
+	assert.EqualError(t, err, "signal: terminated")
+	assert.Equal(t, "terminated", l.usage.Signal)
+	assert.Equal(t, -1, l.usage.ExitCode)
+
+	// The exit codes are kept
+	o = newStopCmd(t, `exit 143`, map[string]any{"sandbox": map[string]any{"namespaces": []any{"pid"}}})
+	l = &linesLog{}
+	err = o.Run(context.Background(), l, &Msg{B: []byte(`{}`)})
+	assert.EqualError(t, err, "exit status 143")
+	assert.Equal(t, "", l.usage.Signal)
+	assert.Equal(t, 143, l.usage.ExitCode)
+}
+
+func TestSandboxResultFd(t *testing.T) {
+	skipWithoutNamespaces(t)
+
+	o := newStopCmd(t, `echo '{"ok":true}' >&3`, map[string]any{
+		"result":  "fd",
+		"sandbox": map[string]any{"namespaces": []any{"pid"}},
+	})
+	b, err := o.RunResult(context.Background(), &linesLog{}, &Msg{B: []byte(`{}`)})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"ok":true}`, string(b))
+}
+
+func TestUnescapeMountInfo(t *testing.T) {
+	assert.Equal(t, "/srv/my dir", unescapeMountInfo(`/srv/my\040dir`))
+	assert.Equal(t, "/usr", unescapeMountInfo("/usr"))
+	assert.Equal(t, `/a\0`, unescapeMountInfo(`/a\0`))
+}
+
+func TestSandboxChrootAndUser(t *testing.T) {
+	skipWithoutNamespaces(t)
+
+	root := t.TempDir()
+	os.Chmod(root, 0755)
+	os.Mkdir(filepath.Join(root, "tmp"), 01777)
+	var readOnly []any
+	for _, p := range []string{"/bin", "/lib", "/lib64", "/usr", "/etc/passwd", "/etc/group"} {
+		if _, err := os.Lstat(p); err == nil {
+			readOnly = append(readOnly, p)
+		}
+	}
+
+	out, err := runSandbox(t, "id -un; pwd; ls /tmp; test -e "+root+" || echo no host", map[string]any{
+		"user": "nobody",
+		"sandbox": map[string]any{
+			"chroot":     root,
+			"readOnly":   readOnly,
+			"privateTmp": true,
+		},
+		"workingDirectory": "/tmp",
+	})
+	assert.NoError(t, err, out)
+	assert.Equal(t, "nobody\n/tmp\nno host\n", out)
+}
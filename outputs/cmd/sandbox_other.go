@@ -0,0 +1,11 @@
+//go:build !linux
+
+package cmd
+
+import (
+	"fmt"
+)
+
+func validSandbox(s *sandbox) error {
+	return fmt.Errorf("sandbox is only supported on linux")
+}
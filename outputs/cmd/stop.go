@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gabrielperezs/goreactor/reactorlog"
+)
+
+const (
+	defaultStopSignal      = "SIGTERM"
+	defaultStopGracePeriod = 10 * time.Second
+
+	stopReasonTimeout  = "timeout"
+	stopReasonShutdown = "shutdown"
+	stopReasonCancel   = "cancelled"
+)
+
+// errShuttingDown is returned by the executions that start after Exit
+var errShuttingDown = errors.New("the command is shutting down")
+
+// stopper stops the process group of the command when its context is done,
+// with the stop signal and SIGKILL after the grace period
+type stopper struct {
+	sync.Mutex
+	o      *Cmd
+	c      *exec.Cmd
+	rl     reactorlog.ReactorLog
+	ctx    context.Context // The context of the execution, with the timeout
+	parent context.Context
+	reason string
+	timer  *time.Timer // SIGKILL after the grace period
+	done   bool        // The execution finished, rl can't be used
+}
+
+func (o *Cmd) newStopper(ctx, parent context.Context, c *exec.Cmd, rl reactorlog.ReactorLog) *stopper {
+	s := &stopper{
+		o:      o,
+		c:      c,
+		rl:     rl,
+		ctx:    ctx,
+		parent: parent,
+	}
+	setProcessGroup(c)
+	c.Cancel = s.cancel
+	// Backup if the process group can't be killed, the pipes are closed
+	// after the grace period to not block Wait
+	c.WaitDelay = o.stopGracePeriod + time.Second
+	return s
+}
+
+// cancel sends the stop signal to the process group, it's called by
+// exec.Cmd when the context is done
+func (s *stopper) cancel() error {
+	s.Lock()
+	defer s.Unlock()
+
+	switch {
+	case s.o.stopping.Err() != nil:
+		s.reason = stopReasonShutdown
+	case errors.Is(s.ctx.Err(), context.DeadlineExceeded) && s.parent.Err() == nil:
+		s.reason = stopReasonTimeout
+	default:
+		s.reason = stopReasonCancel
+	}
+
+	s.log(fmt.Sprintf("stopping the process group with %s: %s", s.o.stopSignalName, s.reason))
+	err := signalGroup(s.c.Process, s.o.stopSignal)
+
+	s.o.running.Add(1)
+	s.timer = time.AfterFunc(s.o.stopGracePeriod, func() {
+		defer s.o.running.Done()
+		s.kill()
+	})
+	return err
+}
+
+// kill sends SIGKILL to the processes of the group that are still running
+func (s *stopper) kill() {
+	s.Lock()
+	defer s.Unlock()
+	if err := signalGroup(s.c.Process, syscall.SIGKILL); err != nil {
+		return // The process group finished
+	}
+	s.log(fmt.Sprintf("killed the process group with SIGKILL after %s: %s", s.o.stopGracePeriod, s.reason))
+}
+
+// log must be called with the lock
+func (s *stopper) log(line string) {
+	log.Printf("CMD %s (pid %d) %s", s.o.cmd, s.c.Process.Pid, line)
+	if !s.done {
+		s.rl.Write([]byte(line + "\n"))
+	}
+}
+
+// finish is called when the process finished, it returns the reason if the
+// process was stopped. SIGKILL is not needed if the process group finished.
+func (s *stopper) finish() string {
+	s.Lock()
+	defer s.Unlock()
+	s.done = true
+	if s.timer != nil && signalGroup(s.c.Process, 0) != nil && s.timer.Stop() {
+		s.o.running.Done()
+	}
+	return s.reason
+}
+
+// start registers a new execution, it fails if the command is exiting
+func (o *Cmd) start() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.exiting {
+		return errShuttingDown
+	}
+	o.running.Add(1)
+	return nil
+}
@@ -0,0 +1,137 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/gabrielperezs/goreactor/lib"
+	"github.com/gabrielperezs/goreactor/reactor"
+	"github.com/stretchr/testify/assert"
+)
+
+type linesLog struct {
+	usageLog
+	mu    sync.Mutex
+	lines strings.Builder
+}
+
+func (l *linesLog) Write(b []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.lines.Write(b)
+}
+
+func newStopCmd(t *testing.T, script string, extra map[string]any) *Cmd {
+	t.Helper()
+	c := map[string]any{
+		"cmd":  "/bin/sh",
+		"args": []any{"-c", script},
+	}
+	for k, v := range extra {
+		c[k] = v
+	}
+	o, err := NewOrGet(reactor.NewReactor(map[string]any{}), c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return o
+}
+
+// alive returns if the process with the pid in the file is running, the
+// zombies that were not reaped yet are not running
+func alive(t *testing.T, pidFile string) bool {
+	t.Helper()
+	b, err := os.ReadFile(pidFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pid := strings.TrimSpace(string(b))
+	if stat, err := os.ReadFile("/proc/" + pid + "/stat"); err == nil {
+		return !strings.Contains(string(stat), ") Z ")
+	}
+	return exec.Command("kill", "-0", pid).Run() == nil
+}
+
+func TestStopSignalConfig(t *testing.T) {
+	o := newStopCmd(t, "true", map[string]any{"stopSignal": "int", "stopGracePeriod": "2s"})
+	assert.Equal(t, syscall.SIGINT, o.stopSignal)
+	assert.Equal(t, 2*time.Second, o.stopGracePeriod)
+
+	o = newStopCmd(t, "true", nil)
+	assert.Equal(t, syscall.SIGTERM, o.stopSignal)
+	assert.Equal(t, defaultStopGracePeriod, o.stopGracePeriod)
+
+	_, err := NewOrGet(nil, map[string]any{"cmd": "true", "stopSignal": "SIGFOO"})
+	assert.Error(t, err)
+	_, err = NewOrGet(nil, map[string]any{"cmd": "true", "stopGracePeriod": "soon"})
+	assert.Error(t, err)
+}
+
+func TestStopProcessGroupOnTimeout(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "pid")
+	// The child keeps the stdout, Wait doesn't finish until it's killed
+	o := newStopCmd(t, `sleep 30 & echo $! > `+pidFile+`; wait`, map[string]any{"maximumCmdTimeLive": "200ms"})
+
+	l := &linesLog{}
+	st := time.Now()
+	err := o.Run(context.Background(), l, &Msg{B: []byte(`{}`)})
+	assert.Error(t, err)
+	assert.Less(t, time.Since(st), 5*time.Second)
+	assert.Contains(t, l.lines.String(), "stopping the process group with SIGTERM: timeout\n")
+	assert.True(t, l.usage.TimedOut)
+	assert.Eventually(t, func() bool { return !alive(t, pidFile) }, time.Second, 10*time.Millisecond)
+}
+
+func TestStopKillAfterGracePeriod(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "pid")
+	o := newStopCmd(t, `trap "" TERM; echo $$ > `+pidFile+`; while true; do sleep 0.05; done`, map[string]any{
+		"maximumCmdTimeLive": "200ms",
+		"stopGracePeriod":    "300ms",
+	})
+
+	l := &linesLog{}
+	err := o.Run(context.Background(), l, &Msg{B: []byte(`{}`)})
+	assert.Error(t, err)
+	assert.Contains(t, l.lines.String(), "killed the process group with SIGKILL after 300ms: timeout\n")
+	assert.Equal(t, "killed", l.usage.Signal)
+	assert.False(t, alive(t, pidFile))
+}
+
+func TestStopOnExit(t *testing.T) {
+	o := newStopCmd(t, "sleep 30", map[string]any{"stopSignal": "SIGINT"})
+
+	l := &linesLog{}
+	done := make(chan error)
+	go func() {
+		done <- o.Run(context.Background(), l, &Msg{B: []byte(`{}`)})
+	}()
+	time.Sleep(200 * time.Millisecond)
+
+	st := time.Now()
+	o.Exit()
+	assert.Less(t, time.Since(st), 5*time.Second)
+	assert.Error(t, <-done)
+	assert.Contains(t, l.lines.String(), "stopping the process group with SIGINT: shutdown\n")
+	assert.False(t, l.usage.TimedOut)
+
+	assert.ErrorIs(t, o.Run(context.Background(), l, &Msg{B: []byte(`{}`)}), errShuttingDown)
+}
+
+func TestRunExtendsDeadline(t *testing.T) {
+	o := newStopCmd(t, "true", map[string]any{"maximumCmdTimeLive": "1m", "timeoutFrom": "$.timeout"})
+	ctx, d := lib.WithDeadline(context.Background())
+
+	st := time.Now()
+	assert.NoError(t, o.Run(ctx, &linesLog{}, &Msg{B: []byte(`{"timeout":5}`)}))
+	assert.False(t, d.Passed(st.Add(4*time.Second)))
+	assert.True(t, d.Passed(time.Now().Add(6*time.Second)))
+}
@@ -0,0 +1,19 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+func parseSignal(name string) (syscall.Signal, error) {
+	return syscall.SIGKILL, nil
+}
+
+// On windows only the process is killed
+func setProcessGroup(c *exec.Cmd) {
+}
+
+func signalGroup(p *os.Process, sig syscall.Signal) error {
+	return p.Kill()
+}
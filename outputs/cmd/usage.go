@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"sync"
+
+	"github.com/gabrielperezs/goreactor/reactorlog"
+)
+
+// countWriter counts the bytes written by the process. The writes are
+// serialized, the result of the stdout is captured apart even if it shares
+// the writer with the stderr.
+type countWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+	n  int64
+}
+
+func (c *countWriter) Write(b []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.n += int64(len(b))
+	return c.w.Write(b)
+}
+
+// processUsage returns the usage of the process that finished
+func processUsage(ps *os.ProcessState, timedOut bool, stdout, stderr *countWriter) reactorlog.Usage {
+	u := reactorlog.Usage{
+		ExitCode:    ps.ExitCode(),
+		TimedOut:    timedOut,
+		UserTime:    ps.UserTime().Seconds(),
+		SysTime:     ps.SystemTime().Seconds(),
+		StdoutBytes: stdout.n,
+	}
+	if stderr != stdout {
+		u.StderrBytes = stderr.n
+	}
+	u.Signal, u.MaxRSS = signalAndMaxRSS(ps)
+	return u
+}
+
+// setUsage records the usage of the process in the END line of the log
+func setUsage(rl reactorlog.ReactorLog, u reactorlog.Usage) {
+	if ul, ok := rl.(reactorlog.UsageLog); ok {
+		ul.SetUsage(u)
+	}
+}
+
+// streamWriters returns the writers of the stdout and the stderr of the
+// process. If the log doesn't keep the streams apart both are the same
+// writer, so the process shares one pipe and the lines keep their order, the
+// bytes of both streams are counted as stdout.
+func streamWriters(rl reactorlog.ReactorLog) (stdout, stderr *countWriter) {
+	sl, ok := rl.(reactorlog.StreamLog)
+	if !ok {
+		w := &countWriter{w: rl}
+		return w, w
+	}
+	return &countWriter{w: sl.Stream(reactorlog.StreamStdout)}, &countWriter{w: sl.Stream(reactorlog.StreamStderr)}
+}
@@ -0,0 +1,82 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/gabrielperezs/goreactor/reactor"
+	"github.com/gabrielperezs/goreactor/reactorlog"
+	"github.com/gabrielperezs/goreactor/reactorlog/noopreactorlog"
+	"github.com/stretchr/testify/assert"
+)
+
+type usageLog struct {
+	noopreactorlog.NoopReactorLog
+	usage *reactorlog.Usage
+}
+
+func (l *usageLog) SetUsage(u reactorlog.Usage) {
+	l.usage = &u
+}
+
+// streamUsageLog keeps the streams apart, as the JSON log
+type streamUsageLog struct {
+	usageLog
+}
+
+func (l *streamUsageLog) Stream(name string) io.Writer {
+	return io.Discard
+}
+
+func runUsage(t *testing.T, script string, timeout string) (*reactorlog.Usage, error) {
+	t.Helper()
+	c := map[string]any{
+		"cmd":                "/bin/sh",
+		"args":               []any{"-c", script},
+		"maximumCmdTimeLive": timeout,
+	}
+	o, err := NewOrGet(reactor.NewReactor(map[string]any{}), c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := &streamUsageLog{}
+	err = o.Run(context.Background(), l, &Msg{B: []byte(`{}`)})
+	return l.usage, err
+}
+
+func TestUsageExitCodeAndBytes(t *testing.T) {
+	u, err := runUsage(t, "printf 12345; printf abc >&2; exit 3", "10s")
+	assert.Error(t, err)
+	assert.NotNil(t, u)
+	assert.Equal(t, 3, u.ExitCode)
+	assert.Equal(t, "", u.Signal)
+	assert.False(t, u.TimedOut)
+	assert.Equal(t, int64(5), u.StdoutBytes)
+	assert.Equal(t, int64(3), u.StderrBytes)
+	assert.Greater(t, u.MaxRSS, int64(0))
+}
+
+func TestUsageSharedStreams(t *testing.T) {
+	// Without the streams apart the process shares one pipe for both
+	o := newStopCmd(t, "printf 'out\n'; printf 'err\n' >&2; printf 'out\n'", nil)
+	l := &linesLog{}
+	assert.NoError(t, o.Run(context.Background(), l, &Msg{B: []byte(`{}`)}))
+	assert.Equal(t, "out\nerr\nout\n", l.lines.String())
+	assert.Equal(t, int64(12), l.usage.StdoutBytes)
+	assert.Equal(t, int64(0), l.usage.StderrBytes)
+
+	stdout, stderr := streamWriters(l)
+	assert.Same(t, stdout, stderr)
+}
+
+func TestUsageTimedOut(t *testing.T) {
+	u, err := runUsage(t, "exec sleep 10", "100ms")
+	assert.Error(t, err)
+	assert.NotNil(t, u)
+	assert.Equal(t, -1, u.ExitCode)
+	assert.Equal(t, "terminated", u.Signal)
+	assert.True(t, u.TimedOut)
+}
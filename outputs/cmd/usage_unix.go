@@ -0,0 +1,25 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+// +build aix darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package cmd
+
+import (
+	"os"
+	"runtime"
+	"syscall"
+)
+
+// signalAndMaxRSS returns the signal that terminated the process and its
+// maximum resident set size in kilobytes
+func signalAndMaxRSS(ps *os.ProcessState) (signal string, maxRSS int64) {
+	if ws, ok := ps.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+		signal = ws.Signal().String()
+	}
+	if ru, ok := ps.SysUsage().(*syscall.Rusage); ok {
+		maxRSS = int64(ru.Maxrss)
+		if runtime.GOOS == "darwin" {
+			maxRSS /= 1024 // In bytes on darwin
+		}
+	}
+	return signal, maxRSS
+}
@@ -5,6 +5,7 @@ package cmd
 
 import (
 	"fmt"
+	"os"
 	"os/exec"
 	"os/user"
 	"strconv"
@@ -23,6 +24,15 @@ func setUserToCmd(u string, finalEnv []string, c *exec.Cmd) error {
 	return nil
 }
 
+// chownToUser gives the file to the user that runs the command
+func chownToUser(path string, u string) error {
+	userCredential, err := getUserCredential(u)
+	if err != nil {
+		return err
+	}
+	return os.Chown(path, int(userCredential.Uid), int(userCredential.Gid))
+}
+
 func setEnvirons(u string, finalEnv []string, c *exec.Cmd) error {
 	gottenUser, err := user.Lookup(u)
 
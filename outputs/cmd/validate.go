@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gabrielperezs/goreactor/lib"
+	"github.com/savaki/jq"
+)
+
+var variableRe = regexp.MustCompile(`^\$\{[\w.]+\}$`)
+
+var uuidRe = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// argRule is a validation rule for the value of a path of the message
+// that is used in the arguments of the command
+type argRule struct {
+	path      string
+	op        jq.Op
+	re        *regexp.Regexp
+	kind      string
+	enum      []string
+	maxLength int
+}
+
+func newArgRule(c map[string]any) (*argRule, error) {
+	r := &argRule{}
+
+	for k, v := range c {
+		switch strings.ToLower(k) {
+		case "path":
+			r.path, _ = v.(string)
+		case "regex":
+			var err error
+			if r.re, err = regexp.Compile("^(?:" + v.(string) + ")$"); err != nil {
+				return nil, err
+			}
+		case "type":
+			r.kind = strings.ToLower(v.(string))
+			switch r.kind {
+			case "int", "uuid", "string":
+			default:
+				return nil, fmt.Errorf("unknown type %s", r.kind)
+			}
+		case "enum":
+			for _, n := range v.([]any) {
+				r.enum = append(r.enum, fmt.Sprint(n))
+			}
+		case "maxlength":
+			n, _ := v.(int64)
+			r.maxLength = int(n)
+		}
+	}
+
+	if variableRe.MatchString(r.path) {
+		return r, nil
+	}
+
+	if !strings.HasPrefix(r.path, "$.") {
+		return nil, fmt.Errorf("invalid path %q, it must start with $. or be a variable like ${S3Key}", r.path)
+	}
+
+	var err error
+	if r.op, err = jq.Parse(r.path[1:]); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// check validates every value found in the path of the message, all the
+// elements if the value is an array. The rules of a variable check the value
+// of the variable in the message
+func (r *argRule) check(msg lib.Msg) error {
+	if r.op == nil {
+		if err := r.checkValue(lib.ReplaceVariables(msg, r.path)); err != nil {
+			return fmt.Errorf("%s: %w", r.path, err)
+		}
+		return nil
+	}
+
+	value, _ := r.op.Apply(msg.Body())
+
+	var values []string
+	if err := json.Unmarshal(value, &values); err != nil {
+		values = []string{string(bytes.Trim(value, "\""))}
+	}
+
+	for _, v := range values {
+		if err := r.checkValue(v); err != nil {
+			return fmt.Errorf("%s: %w", r.path, err)
+		}
+	}
+	return nil
+}
+
+func (r *argRule) checkValue(v string) error {
+	if r.maxLength > 0 && len(v) > r.maxLength {
+		return fmt.Errorf("length %d exceeds the maximum %d", len(v), r.maxLength)
+	}
+
+	switch r.kind {
+	case "int":
+		if _, err := strconv.ParseInt(v, 10, 64); err != nil {
+			return fmt.Errorf("%q is not an integer", v)
+		}
+	case "uuid":
+		if !uuidRe.MatchString(v) {
+			return fmt.Errorf("%q is not an uuid", v)
+		}
+	}
+
+	if len(r.enum) > 0 && !slices.Contains(r.enum, v) {
+		return fmt.Errorf("%q is not one of %s", v, strings.Join(r.enum, ", "))
+	}
+
+	if r.re != nil && !r.re.MatchString(v) {
+		return fmt.Errorf("%q doesn't match %s", v, r.re)
+	}
+	return nil
+}
+
+// substitutedValues returns the arguments of the command that start with a
+// value of the message, a path or a variable like ${Envelope.Name} or ${S3Key},
+// once they are replaced
+func (o *Cmd) substitutedValues(msg lib.Msg) []string {
+	var values []string
+	for _, arg := range o.args {
+		if strings.HasPrefix(arg, "-") || !strings.Contains(arg, "$") {
+			continue
+		}
+		args := o.findReplaceReturningSlice(msg, arg)
+		o.replaceVariablesInArgs(msg, args)
+		values = append(values, args...)
+	}
+	return values
+}
+
+// validate checks the values of the message used in the arguments of the
+// command against the validation rules
+func (o *Cmd) validate(msg lib.Msg) error {
+	for _, r := range o.validations {
+		if err := r.check(msg); err != nil {
+			return err
+		}
+	}
+
+	if o.rejectLeadingDash {
+		for _, v := range o.substitutedValues(msg) {
+			if strings.HasPrefix(v, "-") {
+				return fmt.Errorf("value %q starts with a dash", v)
+			}
+		}
+	}
+	return nil
+}
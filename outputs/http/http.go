@@ -0,0 +1,233 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gabrielperezs/goreactor/lib"
+	"github.com/gabrielperezs/goreactor/reactor"
+	"github.com/gabrielperezs/goreactor/reactorlog"
+)
+
+const (
+	defaultTimeout         = 30 * time.Second
+	defaultMaxResponseSize = 1 << 20 // Bytes of the response body sent to the log
+	correlationIDHeader    = "X-Correlation-Id"
+)
+
+// HTTP is the output that sends a request for every message, the method, URL,
+// headers and body are templates with the values of the message
+type HTTP struct {
+	r               *reactor.Reactor
+	method          string
+	url             string
+	headers         map[string]string
+	body            *string // nil to send the body of the message
+	successCodes    []int   // Empty for any 2xx
+	timeout         time.Duration
+	maxResponseSize int64
+	cond            lib.Conditions
+	client          *http.Client
+}
+
+// NewOrGet create the HTTP output and fill the parameters needed from the
+// config data.
+func NewOrGet(r *reactor.Reactor, c map[string]any) (*HTTP, error) {
+
+	o := &HTTP{
+		r:               r,
+		method:          http.MethodPost,
+		headers:         make(map[string]string),
+		timeout:         defaultTimeout,
+		maxResponseSize: defaultMaxResponseSize,
+	}
+
+	tlsConfig := &tls.Config{}
+	var caFile, certFile, keyFile string
+
+	for k, v := range c {
+		switch strings.ToLower(k) {
+		case "method":
+			o.method = strings.ToUpper(v.(string))
+		case "httpurl":
+			o.url = v.(string)
+		case "headers":
+			for hk, hv := range v.(map[string]any) {
+				o.headers[hk] = fmt.Sprint(hv)
+			}
+		case "body":
+			body := v.(string)
+			o.body = &body
+		case "successcodes":
+			for _, n := range v.([]any) {
+				code, ok := n.(int64)
+				if !ok {
+					return nil, fmt.Errorf("HTTP ERROR: invalid success code %v", n)
+				}
+				o.successCodes = append(o.successCodes, int(code))
+			}
+		case "timeout":
+			var err error
+			if o.timeout, err = time.ParseDuration(v.(string)); err != nil {
+				return nil, fmt.Errorf("HTTP ERROR: invalid timeout: %w", err)
+			}
+		case "maxresponsesize":
+			o.maxResponseSize, _ = v.(int64)
+		case "cond":
+			var err error
+			if o.cond, err = lib.NewConditions(v); err != nil {
+				return nil, fmt.Errorf("HTTP ERROR: %w", err)
+			}
+		case "insecureskipverify":
+			tlsConfig.InsecureSkipVerify, _ = v.(bool)
+		case "servername":
+			tlsConfig.ServerName, _ = v.(string)
+		case "cafile":
+			caFile, _ = v.(string)
+		case "certfile":
+			certFile, _ = v.(string)
+		case "keyfile":
+			keyFile, _ = v.(string)
+		}
+	}
+
+	if o.url == "" {
+		return nil, fmt.Errorf("HTTP ERROR: httpUrl not found or invalid")
+	}
+
+	if o.maxResponseSize <= 0 {
+		o.maxResponseSize = defaultMaxResponseSize
+	}
+
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("HTTP ERROR: %w", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("HTTP ERROR: no certificates found in %s", caFile)
+		}
+	}
+
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("HTTP ERROR: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	o.client = &http.Client{Transport: transport}
+
+	return o, nil
+}
+
+// MatchConditions is a filter of the messages that will be sent
+func (o *HTTP) MatchConditions(msg lib.Msg) error {
+	if !o.cond.Match(msg) {
+		return reactor.ErrInvalidMsgForPlugin
+	}
+	return nil
+}
+
+func (o *HTTP) success(code int) bool {
+	if len(o.successCodes) == 0 {
+		return code >= 200 && code < 300
+	}
+	for _, c := range o.successCodes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+func (o *HTTP) newRequest(ctx context.Context, msg lib.Msg) (*http.Request, error) {
+	var body io.Reader
+	if o.body != nil {
+		body = strings.NewReader(lib.BodyTemplate(msg, *o.body))
+	} else if o.method != http.MethodGet && o.method != http.MethodHead {
+		body = strings.NewReader(string(msg.Body()))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, o.method, lib.URLTemplate(msg, o.url), body)
+	if err != nil {
+		return nil, err
+	}
+
+	for k, v := range o.headers {
+		v = lib.Template(msg, v)
+		if strings.EqualFold(k, "host") {
+			req.Host = v
+			continue
+		}
+		req.Header.Set(k, v)
+	}
+
+	if c, ok := msg.(lib.MsgCorrelation); ok && c.CorrelationID() != "" && req.Header.Get(correlationIDHeader) == "" {
+		req.Header.Set(correlationIDHeader, c.CorrelationID())
+	}
+	return req, nil
+}
+
+// Run sends the request of the message. The response body is written in the
+// log and the status code decides if the message was processed.
+func (o *HTTP) Run(parentCtx context.Context, rl reactorlog.ReactorLog, msg lib.Msg) error {
+
+	if o.r != nil {
+		rl.SetLabel(lib.Template(msg, o.r.Label))
+	}
+	rl.SetHash(msg.GetHash())
+
+	ctx, cancel := context.WithTimeout(parentCtx, o.timeout)
+	defer cancel()
+
+	req, err := o.newRequest(ctx, msg)
+	if err != nil {
+		rl.Write([]byte("invalid request: " + err.Error()))
+		return err
+	}
+
+	rl.Start(0, req.Method+" "+req.URL.Redacted())
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		rl.Write([]byte("error sending request: " + err.Error()))
+		return err
+	}
+	defer resp.Body.Close()
+
+	// Every line of the body is a line of the log
+	scanner := bufio.NewScanner(io.LimitReader(resp.Body, o.maxResponseSize))
+	scanner.Buffer(make([]byte, 64*1024), int(o.maxResponseSize)+1)
+	for scanner.Scan() {
+		rl.Write(append(scanner.Bytes(), '\n'))
+	}
+	if err := scanner.Err(); err != nil {
+		rl.Write([]byte("error reading response: " + err.Error() + "\n"))
+	}
+	io.Copy(io.Discard, resp.Body) // Reuse of the connection
+
+	if !o.success(resp.StatusCode) {
+		log.Printf("HTTP %s %s for %s: %s", req.Method, req.URL.Redacted(), msg.GetHash(), resp.Status)
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// Exit closes the idle connections
+func (o *HTTP) Exit() {
+	o.client.CloseIdleConnections()
+}
@@ -0,0 +1,198 @@
+package http
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gabrielperezs/goreactor/lib"
+	"github.com/gabrielperezs/goreactor/reactor"
+	"github.com/gabrielperezs/goreactor/reactorlog"
+	"github.com/stretchr/testify/assert"
+)
+
+type Msg struct {
+	B    []byte
+	ts   int64
+	hash string
+}
+
+func (m *Msg) Body() []byte {
+	return m.B
+}
+
+func (m *Msg) CreationTimestampMilliseconds() int64 {
+	return m.ts
+}
+
+func (m *Msg) GetHash() string {
+	return m.hash
+}
+
+func (m *Msg) Done() {
+}
+
+func (m *Msg) Wait() {
+}
+
+// testLog keeps what is written in the reactor log
+type testLog struct {
+	strings.Builder
+	started string
+}
+
+func (l *testLog) Start(pid int, s string) {
+	l.started = s
+}
+
+func (l *testLog) SetLabel(string) {
+}
+
+func (l *testLog) SetHash(string) {
+}
+
+func (l *testLog) SetRedactor(*reactorlog.Redactor) {
+}
+
+func (l *testLog) Step(int) reactorlog.ReactorLog {
+	return l
+}
+
+func (l *testLog) Done(error) {
+}
+
+func TestHTTPTemplates(t *testing.T) {
+	var method, path, token, body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+		path = r.URL.RequestURI()
+		token = r.Header.Get("X-Token")
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+		w.Write([]byte("line 1\nline 2\n"))
+	}))
+	defer srv.Close()
+
+	var r *reactor.Reactor = nil
+	o, err := NewOrGet(r, map[string]any{
+		"method":  "put",
+		"httpUrl": srv.URL + "/items/$.id?ts=${CreationTimestampSeconds}",
+		"headers": map[string]any{"X-Token": "token-$.user.name"},
+		"body":    `{"id":"$.id","size":$.size}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer o.Exit()
+
+	var msg lib.Msg = &Msg{
+		B:  []byte(`{"id":"a1","size":3,"user":{"name":"bob"}}`),
+		ts: 1591784694000,
+	}
+
+	rl := &testLog{}
+	assert.NoError(t, o.Run(context.Background(), rl, msg))
+	assert.Equal(t, "PUT", method)
+	assert.Equal(t, "/items/a1?ts=1591784694", path)
+	assert.Equal(t, "token-bob", token)
+	assert.Equal(t, `{"id":"a1","size":3}`, body)
+	assert.Equal(t, "PUT "+srv.URL+"/items/a1?ts=1591784694", rl.started)
+	assert.Equal(t, "line 1\nline 2\n", rl.String())
+}
+
+func TestHTTPTemplatesEscape(t *testing.T) {
+	var path, body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.EscapedPath() + "?" + r.URL.RawQuery
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+	}))
+	defer srv.Close()
+
+	o, err := NewOrGet(nil, map[string]any{
+		"httpUrl": srv.URL + "/items/$.id?q=$.q",
+		"body":    `{"id":"$.id","note":"id $.q"}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer o.Exit()
+
+	msg := &Msg{B: []byte(`{"id":"../admin","q":"a\",\"admin\":true&x=1"}`)}
+	assert.NoError(t, o.Run(context.Background(), &testLog{}, msg))
+	assert.Equal(t, "/items/..%2Fadmin?q=a%22%2C%22admin%22%3Atrue%26x%3D1", path)
+	assert.Equal(t, `{"id":"../admin","note":"id a\",\"admin\":true&x=1"}`, body)
+}
+
+func TestHTTPStatusCodes(t *testing.T) {
+	var body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+		if r.URL.Path == "/accepted" {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte("duplicated"))
+	}))
+	defer srv.Close()
+
+	var msg lib.Msg = &Msg{B: []byte(`{"id":"a1"}`)}
+
+	o, err := NewOrGet(nil, map[string]any{"httpUrl": srv.URL + "/conflict"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rl := &testLog{}
+	assert.ErrorContains(t, o.Run(context.Background(), rl, msg), "409")
+	assert.Equal(t, "duplicated\n", rl.String())
+	assert.Equal(t, `{"id":"a1"}`, body, "Without body the message is sent")
+
+	o, err = NewOrGet(nil, map[string]any{"httpUrl": srv.URL + "/conflict", "successCodes": []any{int64(200), int64(409)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, o.Run(context.Background(), &testLog{}, msg))
+
+	o, err = NewOrGet(nil, map[string]any{"httpUrl": srv.URL + "/accepted", "successCodes": []any{int64(200)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Error(t, o.Run(context.Background(), &testLog{}, msg))
+}
+
+func TestHTTPTLS(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	var msg lib.Msg = &Msg{B: []byte(`{}`)}
+
+	o, err := NewOrGet(nil, map[string]any{"httpUrl": srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Error(t, o.Run(context.Background(), &testLog{}, msg), "Unknown certificate")
+
+	o, err = NewOrGet(nil, map[string]any{"httpUrl": srv.URL, "insecureSkipVerify": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, o.Run(context.Background(), &testLog{}, msg))
+}
+
+func TestHTTPConditions(t *testing.T) {
+	o, err := NewOrGet(nil, map[string]any{
+		"httpUrl": "http://localhost",
+		"cond":    []any{map[string]any{"$.type": "^order$"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NoError(t, o.MatchConditions(&Msg{B: []byte(`{"type":"order"}`)}))
+	assert.Equal(t, reactor.ErrInvalidMsgForPlugin, o.MatchConditions(&Msg{B: []byte(`{"type":"user"}`)}))
+}
@@ -0,0 +1,182 @@
+package outputs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/gabrielperezs/goreactor/lib"
+	"github.com/gabrielperezs/goreactor/reactor"
+	"github.com/gabrielperezs/goreactor/reactorlog"
+)
+
+const (
+	modeSequential = "sequential"
+	modeParallel   = "parallel"
+)
+
+// Multi runs several outputs for every message of the reactor, as sequential
+// steps that stop on the first failure or in parallel. Every step is logged
+// with the same TID of the execution.
+type Multi struct {
+	r     *reactor.Reactor
+	mode  string
+	cond  lib.Conditions
+	steps []lib.Output
+}
+
+// newMulti creates the outputs of the list. The steps inherit the settings of
+// the reactor, like the region or the label, and can override them.
+func newMulti(r *reactor.Reactor, c map[string]any, list any) (*Multi, error) {
+	o := &Multi{
+		r:    r,
+		mode: modeSequential,
+	}
+
+	var stepConfigs []map[string]any
+	switch l := list.(type) {
+	case []map[string]any:
+		stepConfigs = l
+	case []any:
+		for _, n := range l {
+			sc, ok := n.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("Can't read the configuration of the output %v", n)
+			}
+			stepConfigs = append(stepConfigs, sc)
+		}
+	default:
+		return nil, fmt.Errorf("Can't read the configuration (hint: outputs)")
+	}
+
+	if len(stepConfigs) == 0 {
+		return nil, fmt.Errorf("Can't read the configuration (hint: outputs is empty)")
+	}
+
+	for k, v := range c {
+		switch strings.ToLower(k) {
+		case "outputsmode":
+			o.mode = strings.ToLower(v.(string))
+		case "cond":
+			var err error
+			if o.cond, err = lib.NewConditions(v); err != nil {
+				return nil, err
+			}
+		}
+	}
+	base := baseConfig(c)
+
+	if o.mode != modeSequential && o.mode != modeParallel {
+		return nil, fmt.Errorf("Invalid outputsMode %s", o.mode)
+	}
+
+	for i, sc := range stepConfigs {
+		step, err := Get(r, stepConfig(base, sc))
+		if err != nil {
+			o.Exit()
+			return nil, fmt.Errorf("output %d: %w", i+1, err)
+		}
+		o.steps = append(o.steps, step)
+	}
+
+	return o, nil
+}
+
+// stepConfig returns the settings of the reactor replaced by the ones of the
+// step, the names are not case sensitive
+func stepConfig(base, step map[string]any) map[string]any {
+	c := make(map[string]any, len(base)+len(step))
+	for k, v := range base {
+		c[strings.ToLower(k)] = v
+	}
+	for k, v := range step {
+		c[strings.ToLower(k)] = v
+	}
+	return c
+}
+
+// MatchConditions checks the conditions of the reactor, and at least one of
+// the steps must accept the message
+func (o *Multi) MatchConditions(msg lib.Msg) error {
+	if !o.cond.Match(msg) {
+		return reactor.ErrInvalidMsgForPlugin
+	}
+	for _, s := range o.steps {
+		if s.MatchConditions(msg) == nil {
+			return nil
+		}
+	}
+	return reactor.ErrInvalidMsgForPlugin
+}
+
+// runStep runs the output if the message match its conditions, the step
+// has its own log
+func runStep(ctx context.Context, rl reactorlog.ReactorLog, n int, s lib.Output, msg lib.Msg) error {
+	if err := s.MatchConditions(msg); err != nil {
+		return err
+	}
+	srl := rl.Step(n)
+	err := s.Run(ctx, srl, msg)
+	srl.Done(err)
+	return err
+}
+
+// Run executes the steps. The result is an error if any step failed, or
+// ErrInvalidMsgForPlugin if no step accepted the message.
+func (o *Multi) Run(ctx context.Context, rl reactorlog.ReactorLog, msg lib.Msg) error {
+	if o.r != nil {
+		rl.SetLabel(lib.Template(msg, o.r.Label))
+	}
+	rl.SetHash(msg.GetHash())
+
+	errs := make([]error, len(o.steps))
+	if o.mode == modeParallel {
+		var wg sync.WaitGroup
+		for i, s := range o.steps {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs[i] = runStep(ctx, rl, i+1, s, msg)
+			}()
+		}
+		wg.Wait()
+	} else {
+		for i, s := range o.steps {
+			errs[i] = runStep(ctx, rl, i+1, s, msg)
+			if errs[i] != nil && errs[i] != reactor.ErrInvalidMsgForPlugin {
+				rl.Write([]byte(fmt.Sprintf("step %d failed, the next steps are not executed", i+1)))
+				errs = errs[:i+1]
+				break
+			}
+		}
+	}
+
+	var failed []error
+	accepted := false
+	for i, err := range errs {
+		switch err {
+		case nil:
+			accepted = true
+		case reactor.ErrInvalidMsgForPlugin:
+		default:
+			failed = append(failed, fmt.Errorf("step %d: %w", i+1, err))
+		}
+	}
+
+	if len(failed) > 0 {
+		return errors.Join(failed...)
+	}
+	if !accepted {
+		return reactor.ErrInvalidMsgForPlugin
+	}
+	return nil
+}
+
+// Exit finishes all the outputs
+func (o *Multi) Exit() {
+	for _, s := range o.steps {
+		s.Exit()
+	}
+}
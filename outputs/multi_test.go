@@ -0,0 +1,170 @@
+package outputs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/gabrielperezs/goreactor/lib"
+	"github.com/gabrielperezs/goreactor/outputs/cmd"
+	"github.com/gabrielperezs/goreactor/reactor"
+	"github.com/gabrielperezs/goreactor/reactorlog"
+	"github.com/gabrielperezs/goreactor/reactorlog/jsonreactorlog"
+	"github.com/gabrielperezs/goreactor/reactorlog/noopreactorlog"
+	"github.com/stretchr/testify/assert"
+)
+
+type Msg struct {
+	B    []byte
+	ts   int64
+	hash string
+}
+
+func (m *Msg) Body() []byte {
+	return m.B
+}
+
+func (m *Msg) CreationTimestampMilliseconds() int64 {
+	return m.ts
+}
+
+func (m *Msg) GetHash() string {
+	return m.hash
+}
+
+func (m *Msg) Done() {
+}
+
+func (m *Msg) Wait() {
+}
+
+// testOutput returns err for every message and counts the executions
+type testOutput struct {
+	mu    sync.Mutex
+	err   error
+	match bool
+	runs  int
+}
+
+func (o *testOutput) MatchConditions(msg lib.Msg) error {
+	if !o.match {
+		return reactor.ErrInvalidMsgForPlugin
+	}
+	return nil
+}
+
+func (o *testOutput) Run(ctx context.Context, rl reactorlog.ReactorLog, msg lib.Msg) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.runs++
+	rl.Start(0, "test")
+	return o.err
+}
+
+func (o *testOutput) Exit() {
+}
+
+type testLogStream struct {
+	mu    sync.Mutex
+	lines []map[string]any
+}
+
+func (ls *testLogStream) Send(b []byte) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	var line map[string]any
+	json.Unmarshal(b, &line)
+	ls.lines = append(ls.lines, line)
+}
+
+func (ls *testLogStream) Exit() {
+}
+
+func TestMultiSequential(t *testing.T) {
+	failure := errors.New("failure")
+	first := &testOutput{match: true}
+	skipped := &testOutput{match: false}
+	failed := &testOutput{match: true, err: failure}
+	last := &testOutput{match: true}
+
+	o := &Multi{mode: modeSequential, steps: []lib.Output{first, skipped, failed, last}}
+	err := o.Run(context.Background(), noopreactorlog.NoopReactorLog{}, &Msg{B: []byte(`{}`)})
+
+	assert.ErrorIs(t, err, failure)
+	assert.ErrorContains(t, err, "step 3")
+	assert.Equal(t, 1, first.runs)
+	assert.Equal(t, 0, skipped.runs)
+	assert.Equal(t, 1, failed.runs)
+	assert.Equal(t, 0, last.runs, "The steps after a failure are not executed")
+}
+
+func TestMultiParallel(t *testing.T) {
+	failure := errors.New("failure")
+	steps := []*testOutput{{match: true}, {match: true, err: failure}, {match: true}}
+
+	o := &Multi{mode: modeParallel}
+	for _, s := range steps {
+		o.steps = append(o.steps, s)
+	}
+
+	ls := &testLogStream{}
+	rl := jsonreactorlog.NewJSONReactorLog(ls, "host", 1, 7)
+	err := o.Run(context.Background(), rl, &Msg{B: []byte(`{}`), hash: "h1"})
+	rl.Done(err)
+
+	assert.ErrorIs(t, err, failure)
+	for _, s := range steps {
+		assert.Equal(t, 1, s.runs)
+	}
+
+	seen := map[float64]bool{}
+	for _, l := range ls.lines {
+		assert.Equal(t, float64(7), l["TID"])
+		assert.Equal(t, "h1", l["Hash"])
+		if n, ok := l["Step"]; ok {
+			seen[n.(float64)] = true
+		}
+	}
+	assert.Equal(t, map[float64]bool{1: true, 2: true, 3: true}, seen)
+}
+
+func TestMultiNoMatch(t *testing.T) {
+	o := &Multi{mode: modeSequential, steps: []lib.Output{&testOutput{}, &testOutput{}}}
+	msg := &Msg{B: []byte(`{}`)}
+	assert.Equal(t, reactor.ErrInvalidMsgForPlugin, o.MatchConditions(msg))
+	assert.Equal(t, reactor.ErrInvalidMsgForPlugin, o.Run(context.Background(), noopreactorlog.NoopReactorLog{}, msg))
+}
+
+func TestMultiConfig(t *testing.T) {
+	c := map[string]any{
+		"label":       "multi",
+		"user":        "nobody",
+		"outputsMode": "parallel",
+		"cond":        []any{map[string]any{"$.type": "^order$"}},
+		"outputs": []map[string]any{
+			{"output": "cmd", "cmd": "/bin/true"},
+			{"output": "cmd", "cmd": "/bin/echo", "User": ""},
+		},
+	}
+
+	o, err := Get(nil, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := o.(*Multi)
+	assert.Equal(t, modeParallel, m.mode)
+	assert.Len(t, m.steps, 2)
+	assert.IsType(t, &cmd.Cmd{}, m.steps[0])
+
+	assert.NoError(t, m.MatchConditions(&Msg{B: []byte(`{"type":"order"}`)}))
+	assert.Equal(t, reactor.ErrInvalidMsgForPlugin, m.MatchConditions(&Msg{B: []byte(`{"type":"user"}`)}))
+
+	sc := stepConfig(map[string]any{"User": "nobody", "label": "multi"}, map[string]any{"user": ""})
+	assert.Equal(t, map[string]any{"user": "", "label": "multi"}, sc)
+
+	c["outputsMode"] = "random"
+	_, err = Get(nil, c)
+	assert.Error(t, err)
+}
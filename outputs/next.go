@@ -0,0 +1,165 @@
+package outputs
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gabrielperezs/goreactor/lib"
+	"github.com/gabrielperezs/goreactor/reactor"
+	"github.com/gabrielperezs/goreactor/reactorlog"
+)
+
+const correlationIDVariable = "CorrelationId"
+
+// resultMsg is the result of an execution as a new message, the hash of the
+// original message is the correlation id
+type resultMsg struct {
+	body   []byte
+	ts     int64
+	parent lib.Msg
+}
+
+func newResultMsg(body []byte, parent lib.Msg) *resultMsg {
+	return &resultMsg{
+		body:   body,
+		ts:     time.Now().UnixMilli(),
+		parent: parent,
+	}
+}
+
+func (m *resultMsg) Body() []byte {
+	return m.body
+}
+
+func (m *resultMsg) CreationTimestampMilliseconds() int64 {
+	return m.ts
+}
+
+func (m *resultMsg) GetHash() string {
+	return m.parent.GetHash()
+}
+
+func (m *resultMsg) Done() {
+}
+
+func (m *resultMsg) Wait() {
+}
+
+func (m *resultMsg) CorrelationID() string {
+	return m.parent.GetHash()
+}
+
+// Variable returns the ${CorrelationId} and the variables of the original message
+func (m *resultMsg) Variable(name string) (string, bool) {
+	if name == correlationIDVariable {
+		return m.parent.GetHash(), true
+	}
+	if v, ok := m.parent.(lib.MsgVariables); ok {
+		return v.Variable(name)
+	}
+	return "", false
+}
+
+// EnvelopeValue returns the envelope of the original message
+func (m *resultMsg) EnvelopeValue(name string) (string, bool) {
+	if e, ok := m.parent.(lib.MsgEnvelope); ok {
+		return e.EnvelopeValue(name)
+	}
+	return "", false
+}
+
+// Next runs an output that returns a result, and sends the result to the
+// next output
+type Next struct {
+	out  lib.ResultOutput
+	next lib.Output
+}
+
+func newNext(r *reactor.Reactor, c map[string]any, out lib.Output, v any) (*Next, error) {
+	ro, ok := out.(lib.ResultOutput)
+	if !ok {
+		return nil, fmt.Errorf("Can't read the configuration (hint: the output doesn't return results for next)")
+	}
+
+	nc, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("Can't read the configuration (hint: next)")
+	}
+
+	next, err := Get(r, stepConfig(nextBaseConfig(c), nc))
+	if err != nil {
+		return nil, fmt.Errorf("next: %w", err)
+	}
+
+	return &Next{out: ro, next: next}, nil
+}
+
+// baseConfig returns the settings of the reactor without the ones of the
+// outputs, to be inherited by other outputs
+func baseConfig(c map[string]any) map[string]any {
+	base := make(map[string]any, len(c))
+	for k, v := range c {
+		switch strings.ToLower(k) {
+		case "outputs", "output", "outputsmode", "cond", "next", "result":
+		default:
+			base[k] = v
+		}
+	}
+	return base
+}
+
+// nextBaseConfig returns the settings of the reactor inherited by next,
+// without the ones of the command that returns the result
+func nextBaseConfig(c map[string]any) map[string]any {
+	base := baseConfig(c)
+	for k := range base {
+		switch strings.ToLower(k) {
+		case "cmd", "args", "user", "workingdirectory", "env", "validate", "rejectleadingdash", "type",
+			"timeoutfrom", "limits", "sandbox", "stopsignal", "stopgraceperiod":
+			delete(base, k)
+		}
+	}
+	return base
+}
+
+// MatchConditions of the output that returns the result
+func (o *Next) MatchConditions(msg lib.Msg) error {
+	return o.out.MatchConditions(msg)
+}
+
+// Run executes the output, and if it returned a result, the next output with
+// the result. The next output is logged as the step 1 of the execution, or
+// in the same step if the output is a step of several outputs.
+func (o *Next) Run(ctx context.Context, rl reactorlog.ReactorLog, msg lib.Msg) error {
+	result, err := o.out.RunResult(ctx, rl, msg)
+	if err != nil || result == nil {
+		return err
+	}
+
+	rm := newResultMsg(result, msg)
+	if o.next.MatchConditions(rm) != nil {
+		rl.Write([]byte("the result doesn't match the conditions of next\n"))
+		return nil
+	}
+
+	n := 1
+	if s, ok := rl.(reactorlog.StepLog); ok && s.StepNumber() > 0 {
+		n = s.StepNumber()
+	}
+	nrl := rl.Step(n)
+	err = o.next.Run(ctx, nrl, rm)
+	nrl.Done(err)
+	switch err {
+	case nil, reactor.ErrInvalidMsgForPlugin:
+		return nil
+	}
+	return fmt.Errorf("next: %w", err)
+}
+
+// Exit finishes both outputs
+func (o *Next) Exit() {
+	o.out.Exit()
+	o.next.Exit()
+}
@@ -0,0 +1,129 @@
+//go:build !windows
+
+package outputs
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gabrielperezs/goreactor/lib"
+	"github.com/gabrielperezs/goreactor/reactor"
+	"github.com/gabrielperezs/goreactor/reactorlog"
+	"github.com/gabrielperezs/goreactor/reactorlog/jsonreactorlog"
+	"github.com/gabrielperezs/goreactor/reactorlog/noopreactorlog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextResult(t *testing.T) {
+	var path, body, correlation string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+		correlation = r.Header.Get("X-Correlation-Id")
+	}))
+	defer srv.Close()
+
+	r := reactor.NewReactor(map[string]any{})
+	o, err := Get(r, map[string]any{
+		"output": "cmd",
+		"cmd":    "/bin/sh",
+		"args":   []any{"-c", `echo "{\"id\":\"$1\",\"size\":3}"`, "sh", "$.id"},
+		"result": "stdout",
+		"cond":   []any{map[string]any{"$.id": "^a"}},
+		"next": map[string]any{
+			"output":  "http",
+			"httpUrl": srv.URL + "/done/${CorrelationId}",
+			"body":    `{"size":$.size}`,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer o.Exit()
+
+	msg := &Msg{B: []byte(`{"id":"a1"}`), hash: "h1"}
+	assert.NoError(t, o.MatchConditions(msg), "The conditions of the reactor are not applied to the result")
+	assert.Equal(t, reactor.ErrInvalidMsgForPlugin, o.MatchConditions(&Msg{B: []byte(`{"id":"b1"}`)}))
+
+	assert.NoError(t, o.Run(context.Background(), noopreactorlog.NoopReactorLog{}, msg))
+	assert.Equal(t, "/done/h1", path)
+	assert.Equal(t, `{"size":3}`, body)
+	assert.Equal(t, "h1", correlation)
+}
+
+func TestNextWithoutResult(t *testing.T) {
+	_, err := Get(nil, map[string]any{
+		"output":  "http",
+		"httpUrl": "http://localhost",
+		"next":    map[string]any{"output": "http", "httpUrl": "http://localhost"},
+	})
+	assert.Error(t, err)
+}
+
+type resultOutput struct {
+	testOutput
+	result []byte
+}
+
+func (o *resultOutput) RunResult(ctx context.Context, rl reactorlog.ReactorLog, msg lib.Msg) ([]byte, error) {
+	return o.result, o.Run(ctx, rl, msg)
+}
+
+func TestNextStep(t *testing.T) {
+	next := &testOutput{match: true}
+	o := &Next{out: &resultOutput{testOutput: testOutput{match: true}, result: []byte(`{}`)}, next: next}
+
+	ls := &testLogStream{}
+	rl := jsonreactorlog.NewJSONReactorLog(ls, "host", 1, 7)
+	err := o.Run(context.Background(), rl, &Msg{B: []byte(`{}`), hash: "h1"})
+	rl.Done(err)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, next.runs)
+
+	starts := map[any]int{}
+	for _, l := range ls.lines {
+		if l["Status"] == "CMD" {
+			starts[l["Step"]]++
+		}
+	}
+	assert.Equal(t, map[any]int{nil: 1, float64(1): 1}, starts, "The next output is started in its own step")
+}
+
+func TestNextBaseConfig(t *testing.T) {
+	base := nextBaseConfig(map[string]any{
+		"output":            "cmd",
+		"cmd":               "/bin/job",
+		"Args":              []any{"$.id"},
+		"validate":          map[string]any{},
+		"rejectLeadingDash": true,
+		"sandbox":           map[string]any{"privateTmp": true},
+		"result":            "stdout",
+		"region":            "eu-west-1",
+		"label":             "job",
+	})
+	assert.Equal(t, map[string]any{"region": "eu-west-1", "label": "job"}, base)
+}
+
+func TestNextStepOfMulti(t *testing.T) {
+	next := &testOutput{match: true}
+	o := &Next{out: &resultOutput{testOutput: testOutput{match: true}, result: []byte(`{}`)}, next: next}
+
+	ls := &testLogStream{}
+	rl := jsonreactorlog.NewJSONReactorLog(ls, "host", 1, 7)
+	srl := rl.Step(2)
+	err := o.Run(context.Background(), srl, &Msg{B: []byte(`{}`), hash: "h1"})
+	srl.Done(err)
+	rl.Done(err)
+
+	assert.NoError(t, err)
+	for _, l := range ls.lines {
+		if l["Status"] == "CMD" {
+			assert.Equal(t, float64(2), l["Step"], "The next output is logged in the step of the command")
+		}
+	}
+}
@@ -6,6 +6,8 @@ import (
 
 	"github.com/gabrielperezs/goreactor/lib"
 	"github.com/gabrielperezs/goreactor/outputs/cmd"
+	"github.com/gabrielperezs/goreactor/outputs/http"
+	"github.com/gabrielperezs/goreactor/outputs/publish"
 	"github.com/gabrielperezs/goreactor/reactor"
 )
 
@@ -19,17 +21,44 @@ func Get(r *reactor.Reactor, cfg any) (lib.Output, error) {
 		return nil, fmt.Errorf("Can't read the configuration (hint: Output)")
 	}
 
+	for k, v := range c {
+		if strings.ToLower(k) == "outputs" {
+			return newMulti(r, c, v)
+		}
+	}
+
+	var o lib.Output
+	var err error
 	for k, v := range c {
 		switch strings.ToLower(k) {
 		case "output":
 			switch strings.ToLower(v.(string)) {
 			case "cmd":
-				return cmd.NewOrGet(r, c)
+				o, err = cmd.NewOrGet(r, c)
+			case "http":
+				o, err = http.NewOrGet(r, c)
+			case publish.KindSQS, publish.KindSNS:
+				o, err = publish.NewOrGet(r, c, strings.ToLower(v.(string)))
+			case "reactor":
+				o, err = newChain(r, c)
 			default:
 				return nil, fmt.Errorf("Plugin don't exists: %s", k)
 			}
 		}
 	}
 
-	return nil, fmt.Errorf("Unknown error")
+	if err != nil {
+		return nil, err
+	}
+	if o == nil {
+		return nil, fmt.Errorf("Unknown error")
+	}
+
+	// The result of the output is sent to the next one
+	for k, v := range c {
+		if strings.ToLower(k) == "next" {
+			return newNext(r, c, o, v)
+		}
+	}
+	return o, nil
 }
@@ -0,0 +1,227 @@
+package publish
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gabrielperezs/goreactor/lib"
+	"github.com/gabrielperezs/goreactor/lib/awssession"
+	"github.com/gabrielperezs/goreactor/reactor"
+	"github.com/gabrielperezs/goreactor/reactorlog"
+)
+
+const (
+	KindSQS = "sqs"
+	KindSNS = "sns"
+
+	defaultTimeout = 30 * time.Second
+	maxDelay       = 15 * time.Minute // Limit of the SQS delay
+
+	correlationIDAttribute = "CorrelationId"
+)
+
+// publication is the message to be published, with the templates replaced
+type publication struct {
+	message         string
+	attributes      map[string]string
+	delay           int64 // Seconds
+	groupID         string
+	deduplicationID string
+	subject         string
+}
+
+// publisher sends the publications to a queue or topic
+type publisher interface {
+	publish(ctx context.Context, dest string, p *publication) (id string, err error)
+	action() string
+}
+
+// Publish is the output that publishes a new message in a SQS queue or a SNS
+// topic for every message. The new message is a template of the original one.
+type Publish struct {
+	r               *reactor.Reactor
+	kind            string
+	dest            string  // URL of the queue or ARN of the topic
+	message         *string // nil to publish the body of the message
+	attributes      map[string]string
+	delay           time.Duration
+	groupID         string
+	deduplicationID string
+	subject         string
+	timeout         time.Duration
+	cond            lib.Conditions
+	publisher       publisher
+}
+
+// NewOrGet create the output for the kind (sqs or sns) and fill the
+// parameters needed from the config data.
+func NewOrGet(r *reactor.Reactor, c map[string]any, kind string) (*Publish, error) {
+
+	o := &Publish{
+		r:          r,
+		kind:       kind,
+		attributes: make(map[string]string),
+		timeout:    defaultTimeout,
+	}
+	prefix := strings.ToUpper(kind) + " ERROR"
+
+	// The publish keys override the connection settings of the reactor, that
+	// are shared with the input
+	var options awssession.Options
+	publishOptions := make(map[string]any)
+
+	for k, v := range c {
+		switch strings.ToLower(k) {
+		case "queueurl":
+			if kind == KindSQS {
+				o.dest = v.(string)
+			}
+		case "topicarn":
+			if kind == KindSNS {
+				o.dest = v.(string)
+			}
+		case "message":
+			message := v.(string)
+			o.message = &message
+		case "attributes":
+			for ak, av := range v.(map[string]any) {
+				o.attributes[ak] = fmt.Sprint(av)
+			}
+		case "delayseconds":
+			n, _ := v.(int64)
+			o.delay = time.Duration(n) * time.Second
+			if o.delay < 0 || o.delay > maxDelay {
+				return nil, fmt.Errorf("%s: invalid delaySeconds %v", prefix, v)
+			}
+		case "groupid":
+			o.groupID = v.(string)
+		case "deduplicationid":
+			o.deduplicationID = v.(string)
+		case "subject":
+			o.subject = v.(string)
+		case "timeout":
+			var err error
+			if o.timeout, err = time.ParseDuration(v.(string)); err != nil {
+				return nil, fmt.Errorf("%s: invalid timeout: %w", prefix, err)
+			}
+		case "cond":
+			var err error
+			if o.cond, err = lib.NewConditions(v); err != nil {
+				return nil, fmt.Errorf("%s: %w", prefix, err)
+			}
+		default:
+			if name, ok := strings.CutPrefix(strings.ToLower(k), "publish"); ok {
+				if !(&awssession.Options{}).Set(name, v) {
+					return nil, fmt.Errorf("%s: unknown setting %s", prefix, k)
+				}
+				publishOptions[name] = v
+				continue
+			}
+			options.Set(k, v)
+		}
+	}
+	for k, v := range publishOptions {
+		options.Set(k, v)
+	}
+
+	switch {
+	case o.dest == "" && kind == KindSQS:
+		return nil, fmt.Errorf("%s: queueUrl not found or invalid", prefix)
+	case o.dest == "":
+		return nil, fmt.Errorf("%s: topicArn not found or invalid", prefix)
+	case options.Region == "":
+		return nil, fmt.Errorf("%s: Region not found or invalid", prefix)
+	case kind == KindSNS && o.delay > 0:
+		return nil, fmt.Errorf("%s: delaySeconds is not supported by SNS", prefix)
+	case kind == KindSQS && o.subject != "":
+		return nil, fmt.Errorf("%s: subject is not supported by SQS", prefix)
+	}
+
+	sess, cfg, err := awssession.New(options)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", prefix, err)
+	}
+
+	switch kind {
+	case KindSQS:
+		o.publisher = newSQSPublisher(sess, cfg)
+	case KindSNS:
+		o.publisher = newSNSPublisher(sess, cfg)
+	default:
+		return nil, fmt.Errorf("unknown publish output %s", kind)
+	}
+
+	return o, nil
+}
+
+// MatchConditions is a filter of the messages that will be published
+func (o *Publish) MatchConditions(msg lib.Msg) error {
+	if !o.cond.Match(msg) {
+		return reactor.ErrInvalidMsgForPlugin
+	}
+	return nil
+}
+
+// publication replaces the templates with the values of the message, the
+// attributes without value are not published
+func (o *Publish) publication(msg lib.Msg) *publication {
+	p := &publication{
+		message:         string(msg.Body()),
+		attributes:      make(map[string]string, len(o.attributes)),
+		delay:           int64(o.delay.Seconds()),
+		groupID:         lib.Template(msg, o.groupID),
+		deduplicationID: lib.Template(msg, o.deduplicationID),
+		subject:         lib.Template(msg, o.subject),
+	}
+	if o.message != nil {
+		p.message = lib.BodyTemplate(msg, *o.message)
+	}
+	for k, v := range o.attributes {
+		if v = lib.Template(msg, v); v != "" {
+			p.attributes[k] = v
+		}
+	}
+	if c, ok := msg.(lib.MsgCorrelation); ok && c.CorrelationID() != "" && p.attributes[correlationIDAttribute] == "" {
+		p.attributes[correlationIDAttribute] = c.CorrelationID()
+	}
+	return p
+}
+
+// Run publishes the new message
+func (o *Publish) Run(parentCtx context.Context, rl reactorlog.ReactorLog, msg lib.Msg) error {
+
+	if o.r != nil {
+		rl.SetLabel(lib.Template(msg, o.r.Label))
+	}
+	rl.SetHash(msg.GetHash())
+
+	dest := lib.Template(msg, o.dest)
+	p := o.publication(msg)
+	if p.message == "" {
+		rl.Write([]byte("invalid message: empty message to publish"))
+		return fmt.Errorf("empty message to publish")
+	}
+
+	ctx, cancel := context.WithTimeout(parentCtx, o.timeout)
+	defer cancel()
+
+	rl.Start(0, o.publisher.action()+" "+dest)
+
+	id, err := o.publisher.publish(ctx, dest, p)
+	if err != nil {
+		log.Printf("%s %s for %s: %s", o.publisher.action(), dest, msg.GetHash(), err)
+		rl.Write([]byte("error publishing: " + err.Error()))
+		return err
+	}
+
+	rl.Write([]byte("MessageId " + id + "\n"))
+	return nil
+}
+
+// Exit will finish the output
+func (o *Publish) Exit() {
+
+}
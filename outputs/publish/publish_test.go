@@ -0,0 +1,152 @@
+package publish
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sns/snsiface"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+	"github.com/gabrielperezs/goreactor/lib"
+	"github.com/gabrielperezs/goreactor/reactor"
+	"github.com/gabrielperezs/goreactor/reactorlog/noopreactorlog"
+	"github.com/stretchr/testify/assert"
+)
+
+type Msg struct {
+	B    []byte
+	ts   int64
+	hash string
+}
+
+func (m *Msg) Body() []byte {
+	return m.B
+}
+
+func (m *Msg) CreationTimestampMilliseconds() int64 {
+	return m.ts
+}
+
+func (m *Msg) GetHash() string {
+	return m.hash
+}
+
+func (m *Msg) Done() {
+}
+
+func (m *Msg) Wait() {
+}
+
+type fakeSQS struct {
+	sqsiface.SQSAPI
+	sent []*sqs.SendMessageInput
+}
+
+func (f *fakeSQS) SendMessageWithContext(ctx aws.Context, in *sqs.SendMessageInput, opts ...request.Option) (*sqs.SendMessageOutput, error) {
+	f.sent = append(f.sent, in)
+	return &sqs.SendMessageOutput{MessageId: aws.String("m1")}, nil
+}
+
+type fakeSNS struct {
+	snsiface.SNSAPI
+	published []*sns.PublishInput
+}
+
+func (f *fakeSNS) PublishWithContext(ctx aws.Context, in *sns.PublishInput, opts ...request.Option) (*sns.PublishOutput, error) {
+	f.published = append(f.published, in)
+	return &sns.PublishOutput{MessageId: aws.String("m1")}, nil
+}
+
+func TestPublishSQS(t *testing.T) {
+	o, err := NewOrGet(nil, map[string]any{
+		"region":       "eu-west-1",
+		"url":          "https://sqs.eu-west-1.amazonaws.com/1/input",
+		"queueUrl":     "https://sqs.eu-west-1.amazonaws.com/1/orders-$.country",
+		"message":      `{"id":"$.id","items":$.items}`,
+		"attributes":   map[string]any{"Type": "$.type", "Missing": "$.missing"},
+		"delaySeconds": int64(30),
+		"groupId":      "$.customer",
+		"cond":         []any{map[string]any{"$.type": "^order$"}},
+	}, KindSQS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake := &fakeSQS{}
+	o.publisher = &sqsPublisher{svc: fake}
+
+	var msg lib.Msg = &Msg{B: []byte(`{"id":"a\",\"admin\":\"1","type":"order","country":"es","customer":"c1","items":[1,2],"other":true}`)}
+	assert.NoError(t, o.MatchConditions(msg))
+	assert.NoError(t, o.Run(context.Background(), noopreactorlog.NoopReactorLog{}, msg))
+
+	if assert.Len(t, fake.sent, 1) {
+		in := fake.sent[0]
+		assert.Equal(t, "https://sqs.eu-west-1.amazonaws.com/1/orders-es", aws.StringValue(in.QueueUrl))
+		assert.Equal(t, `{"id":"a\",\"admin\":\"1","items":[1,2]}`, aws.StringValue(in.MessageBody), "The values are encoded as JSON")
+		assert.Equal(t, int64(30), aws.Int64Value(in.DelaySeconds))
+		assert.Equal(t, "c1", aws.StringValue(in.MessageGroupId))
+		assert.Len(t, in.MessageAttributes, 1)
+		assert.Equal(t, "order", aws.StringValue(in.MessageAttributes["Type"].StringValue))
+	}
+
+	var other lib.Msg = &Msg{B: []byte(`{"id":"a2","type":"user"}`)}
+	assert.Equal(t, reactor.ErrInvalidMsgForPlugin, o.MatchConditions(other))
+}
+
+func TestPublishSNS(t *testing.T) {
+	o, err := NewOrGet(nil, map[string]any{
+		"region":   "eu-west-1",
+		"topicArn": "arn:aws:sns:eu-west-1:1:events",
+		"subject":  "Order $.id",
+	}, KindSNS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake := &fakeSNS{}
+	o.publisher = &snsPublisher{svc: fake}
+
+	var msg lib.Msg = &Msg{B: []byte(`{"id":"a1"}`)}
+	assert.NoError(t, o.Run(context.Background(), noopreactorlog.NoopReactorLog{}, msg))
+
+	if assert.Len(t, fake.published, 1) {
+		in := fake.published[0]
+		assert.Equal(t, "arn:aws:sns:eu-west-1:1:events", aws.StringValue(in.TopicArn))
+		assert.Equal(t, `{"id":"a1"}`, aws.StringValue(in.Message), "Without message the body is published")
+		assert.Equal(t, "Order a1", aws.StringValue(in.Subject))
+		assert.Nil(t, in.MessageAttributes)
+	}
+}
+
+func TestPublishConfig(t *testing.T) {
+	_, err := NewOrGet(nil, map[string]any{"region": "eu-west-1", "url": "https://sqs.eu-west-1.amazonaws.com/1/input"}, KindSQS)
+	assert.ErrorContains(t, err, "queueUrl")
+
+	_, err = NewOrGet(nil, map[string]any{"region": "eu-west-1", "topicArn": "arn:aws:sns:eu-west-1:1:events", "delaySeconds": int64(5)}, KindSNS)
+	assert.ErrorContains(t, err, "delaySeconds")
+
+	_, err = NewOrGet(nil, map[string]any{"region": "eu-west-1", "queueUrl": "https://sqs.eu-west-1.amazonaws.com/1/q", "delaySeconds": int64(901)}, KindSQS)
+	assert.Error(t, err)
+}
+
+func TestPublishConnection(t *testing.T) {
+	o, err := NewOrGet(nil, map[string]any{
+		"region":          "eu-west-1",
+		"endpoint":        "http://localhost:9324",
+		"url":             "https://sqs.eu-west-1.amazonaws.com/1/input",
+		"queueUrl":        "https://sqs.us-east-1.amazonaws.com/2/orders",
+		"publishRegion":   "us-east-1",
+		"publishRoleArn":  "arn:aws:iam::2:role/publisher",
+		"publishEndpoint": "",
+	}, KindSQS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := o.publisher.(*sqsPublisher).svc.(*sqs.SQS)
+	assert.Equal(t, "us-east-1", aws.StringValue(svc.Client.Config.Region))
+	assert.Equal(t, "https://sqs.us-east-1.amazonaws.com", svc.Client.Endpoint)
+
+	_, err = NewOrGet(nil, map[string]any{"region": "eu-west-1", "queueUrl": "https://sqs.eu-west-1.amazonaws.com/1/q", "publishRegin": "us-east-1"}, KindSQS)
+	assert.ErrorContains(t, err, "publishRegin")
+}
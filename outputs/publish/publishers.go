@@ -0,0 +1,100 @@
+package publish
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sns/snsiface"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+)
+
+const attributeDataType = "String"
+
+type sqsPublisher struct {
+	svc sqsiface.SQSAPI
+}
+
+func newSQSPublisher(sess *session.Session, cfg *aws.Config) *sqsPublisher {
+	return &sqsPublisher{svc: sqs.New(sess, cfg)}
+}
+
+func (s *sqsPublisher) action() string {
+	return "SendMessage"
+}
+
+func (s *sqsPublisher) publish(ctx context.Context, url string, p *publication) (string, error) {
+	input := &sqs.SendMessageInput{
+		QueueUrl:    aws.String(url),
+		MessageBody: aws.String(p.message),
+	}
+	if p.delay > 0 {
+		input.DelaySeconds = aws.Int64(p.delay)
+	}
+	if p.groupID != "" {
+		input.MessageGroupId = aws.String(p.groupID)
+	}
+	if p.deduplicationID != "" {
+		input.MessageDeduplicationId = aws.String(p.deduplicationID)
+	}
+	if len(p.attributes) > 0 {
+		input.MessageAttributes = make(map[string]*sqs.MessageAttributeValue, len(p.attributes))
+		for k, v := range p.attributes {
+			input.MessageAttributes[k] = &sqs.MessageAttributeValue{
+				DataType:    aws.String(attributeDataType),
+				StringValue: aws.String(v),
+			}
+		}
+	}
+
+	out, err := s.svc.SendMessageWithContext(ctx, input)
+	if err != nil {
+		return "", err
+	}
+	return aws.StringValue(out.MessageId), nil
+}
+
+type snsPublisher struct {
+	svc snsiface.SNSAPI
+}
+
+func newSNSPublisher(sess *session.Session, cfg *aws.Config) *snsPublisher {
+	return &snsPublisher{svc: sns.New(sess, cfg)}
+}
+
+func (s *snsPublisher) action() string {
+	return "Publish"
+}
+
+func (s *snsPublisher) publish(ctx context.Context, topicArn string, p *publication) (string, error) {
+	input := &sns.PublishInput{
+		TopicArn: aws.String(topicArn),
+		Message:  aws.String(p.message),
+	}
+	if p.subject != "" {
+		input.Subject = aws.String(p.subject)
+	}
+	if p.groupID != "" {
+		input.MessageGroupId = aws.String(p.groupID)
+	}
+	if p.deduplicationID != "" {
+		input.MessageDeduplicationId = aws.String(p.deduplicationID)
+	}
+	if len(p.attributes) > 0 {
+		input.MessageAttributes = make(map[string]*sns.MessageAttributeValue, len(p.attributes))
+		for k, v := range p.attributes {
+			input.MessageAttributes[k] = &sns.MessageAttributeValue{
+				DataType:    aws.String(attributeDataType),
+				StringValue: aws.String(v),
+			}
+		}
+	}
+
+	out, err := s.svc.PublishWithContext(ctx, input)
+	if err != nil {
+		return "", err
+	}
+	return aws.StringValue(out.MessageId), nil
+}
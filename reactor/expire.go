@@ -0,0 +1,63 @@
+package reactor
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gabrielperezs/goreactor/lib"
+)
+
+const expiredSkip = "skip" // The message is not deleted, as lib.ActionDrop
+
+// ErrExpiredMsg is the error of the messages older than maxMessageAge
+var ErrExpiredMsg = fmt.Errorf("the message expired")
+
+// expiration rejects the messages older than the maximum age
+type expiration struct {
+	maxAge  time.Duration
+	outcome lib.Outcome // What to do with the expired messages
+}
+
+// newExpiration reads the maximum age of the messages and the action for the
+// expired ones: ack (the default), skip or dead-letter
+func newExpiration(maxAge any, action, deadLetterQueue string) (*expiration, error) {
+	d, err := time.ParseDuration(fmt.Sprint(maxAge))
+	if err != nil {
+		return nil, fmt.Errorf("invalid maxMessageAge: %w", err)
+	}
+	if d <= 0 {
+		return nil, fmt.Errorf("invalid maxMessageAge %s, it must be greater than 0", d)
+	}
+
+	e := &expiration{maxAge: d}
+	switch strings.ToLower(action) {
+	case "", lib.ActionAck:
+		e.outcome.Action = lib.ActionAck
+	case expiredSkip, lib.ActionDrop:
+		e.outcome.Action = lib.ActionDrop
+	case lib.ActionDeadLetter:
+		if deadLetterQueue == "" {
+			return nil, fmt.Errorf("expiredAction is dead-letter but deadLetterQueue is not defined")
+		}
+		e.outcome.Action = lib.ActionDeadLetter
+		e.outcome.DeadLetterQueue = deadLetterQueue
+	default:
+		return nil, fmt.Errorf("invalid expiredAction %s, valid values are %s, %s or %s", action, lib.ActionAck, expiredSkip, lib.ActionDeadLetter)
+	}
+	return e, nil
+}
+
+// expired returns the age of the message if it's older than the maximum, the
+// messages without creation timestamp don't expire
+func (e *expiration) expired(msg lib.Msg, now time.Time) (time.Duration, bool) {
+	if e == nil {
+		return 0, false
+	}
+	ts := msg.CreationTimestampMilliseconds()
+	if ts <= 0 {
+		return 0, false
+	}
+	age := now.Sub(time.UnixMilli(ts))
+	return age, age > e.maxAge
+}
@@ -0,0 +1,130 @@
+package reactor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gabrielperezs/goreactor/lib"
+	"github.com/gabrielperezs/goreactor/reactorlog"
+	"github.com/gallir/dynsemaphore"
+	"github.com/stretchr/testify/assert"
+)
+
+type tsMsg struct {
+	Msg
+	ts int64
+}
+
+func (m *tsMsg) CreationTimestampMilliseconds() int64 {
+	return m.ts
+}
+
+type outcomeInput struct {
+	keepAliveInput
+	outcomes []lib.Outcome
+	done     []bool
+}
+
+func (i *outcomeInput) Done(msg lib.Msg, ok bool) {
+	i.done = append(i.done, ok)
+}
+
+func (i *outcomeInput) DoneOutcome(msg lib.Msg, o lib.Outcome) {
+	i.outcomes = append(i.outcomes, o)
+}
+
+type countOutput struct {
+	runs int
+}
+
+func (o *countOutput) MatchConditions(msg lib.Msg) error {
+	return nil
+}
+
+func (o *countOutput) Run(ctx context.Context, rl reactorlog.ReactorLog, msg lib.Msg) error {
+	o.runs++
+	return nil
+}
+
+func (o *countOutput) Exit() {
+}
+
+func TestNewExpiration(t *testing.T) {
+	e, err := newExpiration("10m", "", "")
+	assert.NoError(t, err)
+	assert.Equal(t, &expiration{maxAge: 10 * time.Minute, outcome: lib.Outcome{Action: lib.ActionAck}}, e)
+
+	e, err = newExpiration("1h", "Skip", "")
+	assert.NoError(t, err)
+	assert.Equal(t, lib.ActionDrop, e.outcome.Action)
+
+	e, err = newExpiration("1h", "dead-letter", "https://sqs.eu-west-1.amazonaws.com/1/expired")
+	assert.NoError(t, err)
+	assert.Equal(t, lib.Outcome{Action: lib.ActionDeadLetter, DeadLetterQueue: "https://sqs.eu-west-1.amazonaws.com/1/expired"}, e.outcome)
+
+	for _, cfg := range [][2]string{{"soon", ""}, {"0s", ""}, {"1h", "retry"}, {"1h", "dead-letter"}} {
+		_, err := newExpiration(cfg[0], cfg[1], "")
+		assert.Error(t, err, "%v", cfg)
+	}
+}
+
+func TestExpired(t *testing.T) {
+	e, _ := newExpiration("1m", "", "")
+	now := time.UnixMilli(time.Now().UnixMilli())
+
+	age, expired := e.expired(&tsMsg{ts: now.Add(-2 * time.Minute).UnixMilli()}, now)
+	assert.True(t, expired)
+	assert.Equal(t, 2*time.Minute, age)
+
+	_, expired = e.expired(&tsMsg{ts: now.Add(-30 * time.Second).UnixMilli()}, now)
+	assert.False(t, expired)
+	_, expired = e.expired(&tsMsg{}, now)
+	assert.False(t, expired, "without timestamp")
+	_, expired = (*expiration)(nil).expired(&tsMsg{ts: 1}, now)
+	assert.False(t, expired, "without maxMessageAge")
+}
+
+func TestRunExpiredMessage(t *testing.T) {
+	r := NewReactor(map[string]any{"maxMessageAge": "1m", "expiredAction": "skip"})
+	in := &outcomeInput{}
+	out := &countOutput{}
+	r.I = in
+	r.O = out
+
+	r.run(&tsMsg{ts: time.Now().Add(-time.Hour).UnixMilli()})
+	assert.Equal(t, 0, out.runs)
+	assert.Equal(t, []lib.Outcome{{Action: lib.ActionDrop}}, in.outcomes)
+
+	r.run(&tsMsg{ts: time.Now().UnixMilli()})
+	assert.Equal(t, 1, out.runs)
+	assert.Equal(t, []bool{true}, in.done)
+}
+
+func TestRunExpiredWithoutDelayNorSlot(t *testing.T) {
+	r := NewReactor(map[string]any{"maxMessageAge": "1m"})
+	in := &outcomeInput{}
+	r.I = in
+	r.O = &countOutput{}
+
+	// The reactor waits an hour for the next message, and the global slot is taken
+	r.Delay = time.Hour
+	r.nextDeadline = time.Now()
+	cc := dynsemaphore.New(1)
+	cc.Access()
+	defer cc.Release()
+	r.SetConcurrencyControl(cc)
+
+	done := make(chan struct{})
+	go func() {
+		r.run(&tsMsg{ts: time.Now().Add(-time.Hour).UnixMilli()})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("the expired message waited for the delay or the global slot")
+	}
+	assert.Equal(t, []lib.Outcome{{Action: lib.ActionAck}}, in.outcomes)
+}
@@ -0,0 +1,54 @@
+package reactor
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gabrielperezs/goreactor/lib"
+	"github.com/gabrielperezs/goreactor/reactorlog/noopreactorlog"
+	"github.com/stretchr/testify/assert"
+)
+
+type keepAliveInput struct {
+	keepAlives atomic.Int32
+}
+
+func (i *keepAliveInput) KeepAlive(ctx context.Context, t time.Duration, msg lib.Msg) error {
+	i.keepAlives.Add(1)
+	return nil
+}
+
+func (i *keepAliveInput) Done(msg lib.Msg, ok bool) {
+}
+
+func (i *keepAliveInput) Stop() {
+}
+
+func (i *keepAliveInput) Exit() {
+}
+
+func TestKeepAliveStopsAfterDeadline(t *testing.T) {
+	in := &keepAliveInput{}
+	r := &Reactor{I: in, KeepAliveInterval: 10 * time.Millisecond}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctx, d := lib.WithDeadline(ctx)
+	d.Extend(time.Now().Add(50 * time.Millisecond))
+
+	done := make(chan struct{})
+	go func() {
+		r.KeepAlive(ctx, noopreactorlog.NoopReactorLog{}, &Msg{})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("the keep alive didn't stop after the deadline")
+	}
+	assert.Greater(t, in.keepAlives.Load(), int32(0))
+	assert.Less(t, in.keepAlives.Load(), int32(10))
+}
@@ -0,0 +1,95 @@
+package reactor
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gabrielperezs/goreactor/lib"
+)
+
+// ErrDeadLetter is the error in the log of the executions whose message is
+// sent to the dead-letter queue, to alert on it
+var ErrDeadLetter = errors.New("dead-letter")
+
+// exitCodes maps the exit codes of the commands to the outcome of the message
+type exitCodes map[int]lib.Outcome
+
+// newExitCodes reads the table of exit codes, the values are the action or a
+// table with the action and the delay of the retries
+func newExitCodes(v any, deadLetterQueue string) (exitCodes, error) {
+	cfg, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid exitCodes %v", v)
+	}
+
+	e := make(exitCodes, len(cfg))
+	for k, v := range cfg {
+		code, err := strconv.Atoi(k)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exit code %s", k)
+		}
+
+		o := lib.Outcome{ExitCode: code}
+		switch v := v.(type) {
+		case string:
+			o.Action = v
+		case map[string]any:
+			for name, value := range v {
+				switch strings.ToLower(name) {
+				case "action":
+					o.Action, _ = value.(string)
+				case "delay":
+					if o.Delay, err = time.ParseDuration(fmt.Sprint(value)); err != nil {
+						return nil, fmt.Errorf("invalid delay of exit code %d: %w", code, err)
+					}
+				}
+			}
+		default:
+			return nil, fmt.Errorf("invalid action of exit code %d", code)
+		}
+
+		o.Action = strings.ToLower(o.Action)
+		if o.Action == "drop-without-ack" {
+			o.Action = lib.ActionDrop
+		}
+		switch o.Action {
+		case lib.ActionAck, lib.ActionRetry, lib.ActionDrop:
+		case lib.ActionDeadLetter:
+			if deadLetterQueue == "" {
+				return nil, fmt.Errorf("exit code %d is dead-letter but deadLetterQueue is not defined", code)
+			}
+			o.DeadLetterQueue = deadLetterQueue
+		default:
+			return nil, fmt.Errorf("invalid action %q of exit code %d", o.Action, code)
+		}
+		e[code] = o
+	}
+	return e, nil
+}
+
+// outcome returns the outcome of the exit code of the command that returned
+// the error, false if the error is not an exit code or it's not mapped
+func (e exitCodes) outcome(err error) (lib.Outcome, bool) {
+	var exitErr *exec.ExitError
+	if len(e) == 0 || !errors.As(err, &exitErr) {
+		return lib.Outcome{}, false
+	}
+	o, ok := e[exitErr.ExitCode()]
+	return o, ok
+}
+
+// outcomeError marks the error of the execution when the message is sent to
+// the dead-letter queue
+func outcomeError(o lib.Outcome, err error) error {
+	if o.Action != lib.ActionDeadLetter {
+		return err
+	}
+	if err == nil {
+		return fmt.Errorf("%w %s", ErrDeadLetter, o.DeadLetterQueue)
+	}
+	return fmt.Errorf("%w %s: %w", ErrDeadLetter, o.DeadLetterQueue, err)
+}
@@ -0,0 +1,76 @@
+//go:build !windows
+
+package reactor
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/gabrielperezs/goreactor/lib"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewExitCodes(t *testing.T) {
+	e, err := newExitCodes(map[string]any{
+		"0":  "ack",
+		"75": map[string]any{"action": "retry", "delay": "30s"},
+		"2":  "Dead-Letter",
+		"3":  "drop",
+		"4":  "drop-without-ack",
+	}, "https://sqs.eu-west-1.amazonaws.com/1/dlq")
+	assert.NoError(t, err)
+	assert.Equal(t, lib.Outcome{ExitCode: 75, Action: lib.ActionRetry, Delay: 30 * time.Second}, e[75])
+	assert.Equal(t, lib.Outcome{ExitCode: 2, Action: lib.ActionDeadLetter, DeadLetterQueue: "https://sqs.eu-west-1.amazonaws.com/1/dlq"}, e[2])
+	assert.Equal(t, lib.ActionAck, e[0].Action)
+	assert.Equal(t, lib.ActionDrop, e[3].Action)
+	assert.Equal(t, lib.ActionDrop, e[4].Action)
+
+	for _, cfg := range []any{
+		map[string]any{"2": "dead-letter"},
+		map[string]any{"one": "ack"},
+		map[string]any{"1": "ignore"},
+		map[string]any{"1": map[string]any{"action": "retry", "delay": "soon"}},
+		map[string]any{"1": 5},
+		"ack",
+	} {
+		_, err := newExitCodes(cfg, "")
+		assert.Error(t, err, "%v", cfg)
+	}
+}
+
+func TestExitCodesOutcome(t *testing.T) {
+	e, err := newExitCodes(map[string]any{"75": "retry"}, "")
+	assert.NoError(t, err)
+
+	run := func(code int) error {
+		return exec.Command("sh", "-c", fmt.Sprintf("exit %d", code)).Run()
+	}
+
+	o, ok := e.outcome(fmt.Errorf("cmd: %w", run(75)))
+	assert.True(t, ok)
+	assert.Equal(t, lib.ActionRetry, o.Action)
+
+	_, ok = e.outcome(run(1))
+	assert.False(t, ok)
+	_, ok = e.outcome(errors.New("timeout"))
+	assert.False(t, ok)
+	_, ok = exitCodes(nil).outcome(run(75))
+	assert.False(t, ok)
+}
+
+func TestOutcomeError(t *testing.T) {
+	dlq := lib.Outcome{Action: lib.ActionDeadLetter, DeadLetterQueue: "https://sqs.eu-west-1.amazonaws.com/1/dlq"}
+	cmdErr := errors.New("exit status 2")
+
+	err := outcomeError(dlq, cmdErr)
+	assert.ErrorIs(t, err, ErrDeadLetter)
+	assert.ErrorIs(t, err, cmdErr)
+	assert.Equal(t, "dead-letter https://sqs.eu-west-1.amazonaws.com/1/dlq: exit status 2", err.Error())
+	assert.ErrorIs(t, outcomeError(dlq, nil), ErrDeadLetter)
+
+	assert.Equal(t, cmdErr, outcomeError(lib.Outcome{Action: lib.ActionRetry}, cmdErr))
+	assert.NoError(t, outcomeError(lib.Outcome{Action: lib.ActionAck}, nil))
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"regexp"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -47,6 +48,12 @@ type Reactor struct {
 	done              chan bool
 	logStream         lib.LogStream
 	cc                *dynsemaphore.DynSemaphore
+	redaction         *reactorlog.Redaction
+	environment       []string
+	verifier          verifier
+	exitCodes         exitCodes
+	streams           streamRoutes
+	expiration        *expiration
 }
 
 // NewReactor will create a reactor with the configuration
@@ -81,6 +88,15 @@ func (r *Reactor) Reload(icfg any) {
 		return
 	}
 
+	redaction := &reactorlog.Redaction{}
+	r.environment = nil
+	r.exitCodes = nil
+	r.streams.exit()
+	r.streams = make(streamRoutes)
+	r.expiration = nil
+	var exitCodesCfg, maxMessageAge any
+	var deadLetterQueue, expiredAction string
+
 	for k, v := range cfg {
 		switch strings.ToLower(k) {
 		case "concurrent":
@@ -99,9 +115,69 @@ func (r *Reactor) Reload(icfg any) {
 			if err != nil {
 				r.KeepAliveInterval = 0
 			}
+		case "redact":
+			for _, n := range v.([]any) {
+				re, err := regexp.Compile(n.(string))
+				if err != nil {
+					log.Printf("ERROR Reactor redact %s: %s", n, err)
+					continue
+				}
+				redaction.Regexps = append(redaction.Regexps, re)
+			}
+		case "redactpaths":
+			for _, n := range v.([]any) {
+				redaction.Paths = append(redaction.Paths, n.(string))
+			}
+		case "redactenv":
+			for _, n := range v.([]any) {
+				redaction.Env = append(redaction.Env, n.(string))
+			}
+		case "env":
+			for _, n := range v.([]any) {
+				r.environment = append(r.environment, n.(string))
+			}
+		case "exitcodes":
+			exitCodesCfg = v
+		case "deadletterqueue":
+			deadLetterQueue, _ = v.(string)
+		case "maxmessageage":
+			maxMessageAge = v
+		case "expiredaction":
+			expiredAction, _ = v.(string)
+		case reactorlog.StreamStdout, reactorlog.StreamStderr:
+			if err := r.streams.set(strings.ToLower(k), v); err != nil {
+				log.Printf("ERROR Reactor %s", err)
+			}
+		case "verify":
+			var err error
+			r.verifier, err = newVerifier(v.(map[string]any))
+			if err != nil {
+				// Reject all the messages rather than accepting unverified ones
+				log.Printf("ERROR Reactor verify: %s", err)
+				r.verifier = rejectVerifier{err: fmt.Errorf("invalid verify configuration: %w", err)}
+			}
 		}
 	}
 
+	if exitCodesCfg != nil {
+		var err error
+		if r.exitCodes, err = newExitCodes(exitCodesCfg, deadLetterQueue); err != nil {
+			log.Printf("ERROR Reactor exitCodes: %s", err)
+		}
+	}
+
+	if maxMessageAge != nil {
+		var err error
+		if r.expiration, err = newExpiration(maxMessageAge, expiredAction, deadLetterQueue); err != nil {
+			log.Printf("ERROR Reactor %s", err)
+		}
+	}
+
+	r.redaction = nil
+	if !redaction.Empty() {
+		r.redaction = redaction
+	}
+
 	if r.Concurrent <= 0 {
 		r.Concurrent = 1
 	}
@@ -123,8 +199,15 @@ func (r *Reactor) SetHostname(name string) error {
 	return nil
 }
 
-// MatchConditions will call to the MatchConditions of the Output
+// MatchConditions will verify the message, if required, and call to the
+// MatchConditions of the Output
 func (r *Reactor) MatchConditions(msg lib.Msg) error {
+	if r.verifier != nil {
+		if err := r.verifier.Verify(msg); err != nil {
+			log.Printf("Reactor %d rejected message %s: %s", r.id, msg.GetHash(), err)
+			return ErrInvalidMsgForPlugin
+		}
+	}
 	return r.O.MatchConditions(msg)
 }
 
@@ -154,6 +237,7 @@ func (r *Reactor) Exit() {
 	if r.logStream != nil {
 		r.logStream.Exit()
 	}
+	r.streams.exit()
 }
 
 func (r *Reactor) listener() {
@@ -189,34 +273,83 @@ func (r *Reactor) deadline() {
 }
 
 func (r *Reactor) run(msg lib.Msg) {
+	// The expired messages don't wait for the delay nor a global slot
+	if age, expired := r.expiration.expired(msg, time.Now()); expired {
+		r.expired(r.newLog(msg), msg, age)
+		return
+	}
+
 	r.deadline()
 
+	// The messages of other reactors don't take a global slot, the sender
+	// holds one while it waits for them
 	cc := r.cc
+	if c, ok := msg.(lib.MsgChained); ok && c.Chained() {
+		cc = nil
+	}
 	if cc != nil {
 		cc.Access()
 		defer cc.Release()
 	}
 
-	var err error
-	var rl reactorlog.ReactorLog = noopreactorlog.NoopReactorLog{}
-	if r.logStream != nil {
-		rl = jsonreactorlog.NewJSONReactorLog(r.logStream, r.Hostname, r.id, atomic.AddUint64(&r.tid, 1))
-	}
+	rl := r.newLog(msg)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
+	ctx, _ = lib.WithDeadline(ctx)
 
 	// run keep alive go routine if needed
 	if r.KeepAliveInterval > 0 {
 		go r.KeepAlive(ctx, rl, msg)
 	}
 
-	err = r.O.Run(ctx, rl, msg)
-	ok := err == nil || err == ErrInvalidMsgForPlugin
-	r.I.Done(msg, ok) // To remove this message from the pending message queue
+	err := r.O.Run(ctx, rl, msg)
+	cancel() // Stop the keep alive before the message is done
+
+	if o, found := r.exitCodes.outcome(err); found {
+		rl.Write([]byte(fmt.Sprintf("exit code %d: %s\n", o.ExitCode, o.Action)))
+		r.doneOutcome(msg, o)
+		err = outcomeError(o, err)
+	} else {
+		ok := err == nil || err == ErrInvalidMsgForPlugin
+		r.I.Done(msg, ok) // To remove this message from the pending message queue
+	}
 	rl.Done(err)
 }
 
+// newLog creates the log of the execution of the message
+func (r *Reactor) newLog(msg lib.Msg) reactorlog.ReactorLog {
+	var rl reactorlog.ReactorLog = noopreactorlog.NoopReactorLog{}
+	if r.logStream != nil {
+		jrl := jsonreactorlog.NewJSONReactorLog(r.logStream, r.Hostname, r.id, atomic.AddUint64(&r.tid, 1))
+		r.streams.route(jrl)
+		rl = jrl
+	}
+	rl.SetRedactor(r.redaction.Redactor(msg.Body(), r.environment))
+	return rl
+}
+
+// expired doesn't run the message, that is older than maxMessageAge
+func (r *Reactor) expired(rl reactorlog.ReactorLog, msg lib.Msg, age time.Duration) {
+	o := r.expiration.outcome
+	log.Printf("Reactor %d expired message %s, age %s: %s", r.id, msg.GetHash(), age.Round(time.Second), o.Action)
+	rl.SetLabel(lib.Template(msg, r.Label))
+	rl.SetHash(msg.GetHash())
+	rl.Write([]byte(fmt.Sprintf("expired message, age %s older than maxMessageAge %s: %s\n", age.Round(time.Second), r.expiration.maxAge, o.Action)))
+	r.doneOutcome(msg, o)
+	rl.Done(outcomeError(o, ErrExpiredMsg))
+}
+
+// doneOutcome sends the outcome to the input, or only if it's processed if
+// the input doesn't support the outcomes
+func (r *Reactor) doneOutcome(msg lib.Msg, o lib.Outcome) {
+	if oi, ok := r.I.(lib.OutcomeInput); ok {
+		oi.DoneOutcome(msg, o)
+		return
+	}
+	r.I.Done(msg, o.Action == lib.ActionAck)
+}
+
 func (r *Reactor) KeepAlive(ctx context.Context, rl reactorlog.ReactorLog, msg lib.Msg) {
 	t := time.NewTicker(r.KeepAliveInterval)
 	defer t.Stop()
@@ -224,6 +357,12 @@ func (r *Reactor) KeepAlive(ctx context.Context, rl reactorlog.ReactorLog, msg l
 	for {
 		select {
 		case <-t.C:
+			// The message is released if the execution didn't finish in time
+			if lib.DeadlineFrom(ctx).Passed(time.Now()) {
+				rl.Write([]byte("keepalive stopped, the deadline of the execution passed\n"))
+				return
+			}
+
 			keepAliveCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
 			err := r.I.KeepAlive(keepAliveCtx, r.KeepAliveInterval, msg)
 			cancel()
@@ -0,0 +1,57 @@
+package reactor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gabrielperezs/goreactor/lib"
+	"github.com/gabrielperezs/goreactor/logstreams"
+	"github.com/gabrielperezs/goreactor/reactorlog/jsonreactorlog"
+)
+
+const (
+	streamLog     = "log"     // The lines go to the logstream of goreactor, the default
+	streamDiscard = "discard" // The lines are not logged
+)
+
+// streamRoutes are the logstreams of the streams of the process that don't go
+// to the logstream of goreactor, nil if the stream is discarded
+type streamRoutes map[string]lib.LogStream
+
+// set reads the route of the stream, the values are log, discard or the
+// configuration of a logstream
+func (s streamRoutes) set(name string, v any) error {
+	switch v := v.(type) {
+	case string:
+		switch strings.ToLower(v) {
+		case streamLog:
+		case streamDiscard:
+			s[name] = nil
+		default:
+			return fmt.Errorf("invalid %s %s, valid values are %s, %s or a logstream", name, v, streamLog, streamDiscard)
+		}
+	case map[string]any:
+		ls, err := logstreams.Get(v)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		s[name] = ls
+	default:
+		return fmt.Errorf("invalid %s %v", name, v)
+	}
+	return nil
+}
+
+func (s streamRoutes) route(rl *jsonreactorlog.JSONReactorLog) {
+	for name, ls := range s {
+		rl.RouteStream(name, ls)
+	}
+}
+
+func (s streamRoutes) exit() {
+	for _, ls := range s {
+		if ls != nil {
+			ls.Exit()
+		}
+	}
+}
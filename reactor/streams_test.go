@@ -0,0 +1,22 @@
+package reactor
+
+import (
+	"testing"
+
+	"github.com/gabrielperezs/goreactor/logstreams/localstream"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamRoutes(t *testing.T) {
+	s := make(streamRoutes)
+	assert.NoError(t, s.set("stdout", "log"))
+	assert.NoError(t, s.set("stderr", "Discard"))
+	assert.Equal(t, streamRoutes{"stderr": nil}, s)
+
+	assert.NoError(t, s.set("stdout", map[string]any{"logstream": "stdout"}))
+	assert.Equal(t, localstream.LogStream{}, s["stdout"])
+
+	assert.Error(t, s.set("stdout", "file"))
+	assert.Error(t, s.set("stdout", map[string]any{"logstream": "none"}))
+	assert.Error(t, s.set("stdout", true))
+}
@@ -0,0 +1,297 @@
+package reactor
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gabrielperezs/goreactor/lib"
+	"github.com/savaki/jq"
+)
+
+const (
+	defaultSNSMaxAge = time.Hour
+	snsClockSkew     = 5 * time.Minute // Allowed for the timestamps in the future
+)
+
+// verifier checks the authenticity of a message before it's accepted by
+// the reactor
+type verifier interface {
+	Verify(msg lib.Msg) error
+}
+
+// newVerifier creates the verifier defined in the "verify" table of the reactor
+func newVerifier(cfg map[string]any) (verifier, error) {
+	var kind string
+	for k, v := range cfg {
+		if strings.ToLower(k) == "type" {
+			kind, _ = v.(string)
+		}
+	}
+
+	switch strings.ToLower(kind) {
+	case "hmac":
+		return newHMACVerifier(cfg)
+	case "sns":
+		return newSNSVerifier(cfg)
+	default:
+		return nil, fmt.Errorf("unknown verify type %q", kind)
+	}
+}
+
+// rejectVerifier rejects all the messages, it's used when the verify
+// configuration is wrong
+type rejectVerifier struct {
+	err error
+}
+
+func (v rejectVerifier) Verify(lib.Msg) error {
+	return v.err
+}
+
+// rawBody returns the body as it was received, before removing the envelope or
+// splitting the records
+func rawBody(msg lib.Msg) []byte {
+	if m, ok := msg.(lib.MsgRaw); ok && len(m.RawBody()) > 0 {
+		return m.RawBody()
+	}
+	return msg.Body()
+}
+
+// hmacVerifier checks a HMAC-SHA256 signature of the body, or of a path of the
+// body, taken from a message attribute (attr:Name) or from the body ($.path).
+// The body is the one received, before removing the envelope or splitting the
+// records.
+type hmacVerifier struct {
+	secret    []byte
+	signature string
+	content   jq.Op
+	encoding  string
+}
+
+func newHMACVerifier(cfg map[string]any) (*hmacVerifier, error) {
+	v := &hmacVerifier{
+		encoding: "hex",
+	}
+
+	for k, nv := range cfg {
+		switch strings.ToLower(k) {
+		case "secret":
+			s, _ := nv.(string)
+			v.secret = []byte(s)
+			lib.AddSecret(s)
+		case "signature":
+			v.signature, _ = nv.(string)
+		case "content":
+			s, _ := nv.(string)
+			op, err := jq.Parse(strings.TrimPrefix(s, "$"))
+			if err != nil {
+				return nil, fmt.Errorf("invalid content path %s: %w", s, err)
+			}
+			v.content = op
+		case "encoding":
+			v.encoding, _ = nv.(string)
+			v.encoding = strings.ToLower(v.encoding)
+		}
+	}
+
+	if len(v.secret) == 0 {
+		return nil, fmt.Errorf("hmac secret is empty")
+	}
+	if !strings.HasPrefix(v.signature, "attr:") && !strings.HasPrefix(v.signature, "$.") {
+		return nil, fmt.Errorf("hmac signature must be attr:Name or $.path")
+	}
+	// The body with the signature can't be the signed content
+	if strings.HasPrefix(v.signature, "$.") && v.content == nil {
+		return nil, fmt.Errorf("hmac signature %s requires the content, the path of the signed content", v.signature)
+	}
+	if v.encoding != "hex" && v.encoding != "base64" {
+		return nil, fmt.Errorf("unknown hmac encoding %s", v.encoding)
+	}
+	return v, nil
+}
+
+func (v *hmacVerifier) Verify(msg lib.Msg) error {
+	var signature string
+	if name, ok := strings.CutPrefix(v.signature, "attr:"); ok {
+		attrs, ok := msg.(lib.MsgAttributes)
+		if !ok {
+			return fmt.Errorf("message without attributes")
+		}
+		if signature, ok = attrs.Attribute(name); !ok {
+			return fmt.Errorf("signature attribute %s not found", name)
+		}
+	} else {
+		op, err := jq.Parse(v.signature[1:])
+		if err != nil {
+			return err
+		}
+		value, err := op.Apply(rawBody(msg))
+		if err != nil {
+			return fmt.Errorf("signature %s not found", v.signature)
+		}
+		signature = string(bytes.Trim(value, "\""))
+	}
+	signature = strings.TrimPrefix(signature, "sha256=")
+
+	var sig []byte
+	var err error
+	if v.encoding == "base64" {
+		sig, err = base64.StdEncoding.DecodeString(signature)
+	} else {
+		sig, err = hex.DecodeString(signature)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid signature encoding: %w", err)
+	}
+
+	content := rawBody(msg)
+	if v.content != nil {
+		if content, err = v.content.Apply(content); err != nil {
+			return fmt.Errorf("signed content not found: %w", err)
+		}
+		var s string
+		if json.Unmarshal(content, &s) == nil {
+			content = []byte(s) // Strings are signed without the quotes
+		}
+	}
+
+	mac := hmac.New(sha256.New, v.secret)
+	mac.Write(content)
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return fmt.Errorf("invalid signature")
+	}
+	return nil
+}
+
+// snsVerifier validates the signature of the SNS envelope with a
+// certificate stored locally, and that the message is not older than maxAge
+// to limit the replays
+type snsVerifier struct {
+	key    *rsa.PublicKey
+	maxAge time.Duration
+}
+
+func newSNSVerifier(cfg map[string]any) (*snsVerifier, error) {
+	var path string
+	maxAge := defaultSNSMaxAge
+	for k, nv := range cfg {
+		switch strings.ToLower(k) {
+		case "certificate":
+			path, _ = nv.(string)
+		case "maxage":
+			var err error
+			if maxAge, err = time.ParseDuration(fmt.Sprint(nv)); err != nil || maxAge <= 0 {
+				return nil, fmt.Errorf("invalid SNS maxAge %v", nv)
+			}
+		}
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM certificate found in %s", path)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("the certificate %s has not a RSA public key", path)
+	}
+	return &snsVerifier{key: key, maxAge: maxAge}, nil
+}
+
+// snsEnvelope contains the fields of a SNS message used in the signature
+type snsEnvelope struct {
+	Type             string
+	MessageId        string
+	Token            string
+	TopicArn         string
+	Subject          *string
+	Message          string
+	Timestamp        string
+	SubscribeURL     string
+	SignatureVersion string
+	Signature        string
+}
+
+// stringToSign builds the string signed by SNS
+// https://docs.aws.amazon.com/sns/latest/dg/sns-verify-signature-of-message.html
+func (e *snsEnvelope) stringToSign() string {
+	var b strings.Builder
+	add := func(k, v string) {
+		b.WriteString(k + "\n" + v + "\n")
+	}
+
+	add("Message", e.Message)
+	add("MessageId", e.MessageId)
+	if e.Type == "Notification" {
+		if e.Subject != nil {
+			add("Subject", *e.Subject)
+		}
+	} else {
+		add("SubscribeURL", e.SubscribeURL)
+	}
+	add("Timestamp", e.Timestamp)
+	if e.Type != "Notification" {
+		add("Token", e.Token)
+	}
+	add("TopicArn", e.TopicArn)
+	add("Type", e.Type)
+	return b.String()
+}
+
+func (v *snsVerifier) Verify(msg lib.Msg) error {
+	e := &snsEnvelope{}
+	if err := json.Unmarshal(rawBody(msg), e); err != nil {
+		return fmt.Errorf("invalid SNS envelope: %w", err)
+	}
+
+	ts, err := time.Parse(time.RFC3339, e.Timestamp)
+	if err != nil {
+		return fmt.Errorf("invalid SNS timestamp %q", e.Timestamp)
+	}
+	if age := time.Since(ts); age > v.maxAge || age < -snsClockSkew {
+		return fmt.Errorf("SNS timestamp %s out of the maxAge %s", e.Timestamp, v.maxAge)
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(e.Signature)
+	if err != nil {
+		return fmt.Errorf("invalid SNS signature encoding: %w", err)
+	}
+
+	var hash crypto.Hash
+	var digest []byte
+	switch e.SignatureVersion {
+	case "1":
+		h := sha1.Sum([]byte(e.stringToSign()))
+		hash, digest = crypto.SHA1, h[:]
+	case "2":
+		h := sha256.Sum256([]byte(e.stringToSign()))
+		hash, digest = crypto.SHA256, h[:]
+	default:
+		return fmt.Errorf("unknown SNS signature version %q", e.SignatureVersion)
+	}
+
+	if err := rsa.VerifyPKCS1v15(v.key, hash, digest, sig); err != nil {
+		return fmt.Errorf("invalid SNS signature")
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package reactor
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type Msg struct {
+	B     []byte
+	raw   []byte
+	attrs map[string]string
+}
+
+func (m *Msg) Body() []byte {
+	return m.B
+}
+
+func (m *Msg) RawBody() []byte {
+	return m.raw
+}
+
+func (m *Msg) CreationTimestampMilliseconds() int64 {
+	return 0
+}
+
+func (m *Msg) GetHash() string {
+	return ""
+}
+
+func (m *Msg) Done() {
+}
+
+func (m *Msg) Wait() {
+}
+
+func (m *Msg) Attribute(name string) (string, bool) {
+	v, ok := m.attrs[name]
+	return v, ok
+}
+
+func sign(secret string, b []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(b)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestHMACVerifierAttribute(t *testing.T) {
+	v, err := newVerifier(map[string]any{
+		"type":      "hmac",
+		"secret":    "shared",
+		"signature": "attr:X-Signature",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body := []byte(`{"cmd":"deploy"}`)
+	assert.NoError(t, v.Verify(&Msg{B: body, attrs: map[string]string{"X-Signature": sign("shared", body)}}))
+	assert.NoError(t, v.Verify(&Msg{B: body, attrs: map[string]string{"X-Signature": "sha256=" + sign("shared", body)}}))
+	assert.Error(t, v.Verify(&Msg{B: body, attrs: map[string]string{"X-Signature": sign("other", body)}}))
+	assert.Error(t, v.Verify(&Msg{B: body}))
+
+	// A record split from the signed body
+	assert.NoError(t, v.Verify(&Msg{B: []byte(`{}`), raw: body, attrs: map[string]string{"X-Signature": sign("shared", body)}}))
+}
+
+func TestHMACVerifierBodyField(t *testing.T) {
+	v, err := newVerifier(map[string]any{
+		"type":      "hmac",
+		"secret":    "shared",
+		"signature": "$.signature",
+		"content":   "$.payload",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body := []byte(`{"payload":"deploy web","signature":"` + sign("shared", []byte("deploy web")) + `"}`)
+	assert.NoError(t, v.Verify(&Msg{B: body}))
+
+	tampered := []byte(`{"payload":"deploy db","signature":"` + sign("shared", []byte("deploy web")) + `"}`)
+	assert.Error(t, v.Verify(&Msg{B: tampered}))
+
+	_, err = newVerifier(map[string]any{"type": "hmac", "secret": "shared", "signature": "$.signature"})
+	assert.ErrorContains(t, err, "requires the content")
+}
+
+func TestSNSVerifier(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "sns.eu-west-1.amazonaws.com"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile := filepath.Join(t.TempDir(), "sns.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := newVerifier(map[string]any{"type": "sns", "certificate": certFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := &snsEnvelope{
+		Type:             "Notification",
+		MessageId:        "1",
+		TopicArn:         "arn:aws:sns:eu-west-1:9999999999:events",
+		Message:          `{"Event":"launch"}`,
+		Timestamp:        time.Now().UTC().Format("2006-01-02T15:04:05.000Z"),
+		SignatureVersion: "2",
+	}
+	signEnvelope := func() []byte {
+		digest := sha256.Sum256([]byte(e.stringToSign()))
+		sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		e.Signature = base64.StdEncoding.EncodeToString(sig)
+		raw, _ := json.Marshal(e)
+		return raw
+	}
+
+	raw := signEnvelope()
+	assert.NoError(t, v.Verify(&Msg{B: []byte(e.Message), raw: raw}))
+
+	e.Message = `{"Event":"terminate"}`
+	raw, _ = json.Marshal(e)
+	assert.Error(t, v.Verify(&Msg{B: []byte(e.Message), raw: raw}))
+
+	// Replay of an old message
+	e.Timestamp = time.Now().Add(-2 * time.Hour).UTC().Format("2006-01-02T15:04:05.000Z")
+	raw = signEnvelope()
+	assert.ErrorContains(t, v.Verify(&Msg{B: []byte(e.Message), raw: raw}), "maxAge")
+
+	v, err = newVerifier(map[string]any{"type": "sns", "certificate": certFile, "maxAge": "3h"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, v.Verify(&Msg{B: []byte(e.Message), raw: raw}))
+}
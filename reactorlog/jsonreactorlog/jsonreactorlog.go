@@ -4,11 +4,13 @@ import (
 	"bytes"
 	"encoding/json"
 	"log"
+	"maps"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/gabrielperezs/goreactor/lib"
+	"github.com/gabrielperezs/goreactor/reactorlog"
 )
 
 var (
@@ -29,6 +31,7 @@ func NewJSONReactorLog(logStream lib.LogStream, hostname string, rid uint64, tid
 	r.logStream = logStream
 	r.RID = rid
 	r.TID = tid
+	r.StepN = 0
 	r.Status = ""
 	r.st = time.Now()
 	r.Timestamp = r.st.Unix()
@@ -44,21 +47,28 @@ type ReactorLog interface {
 
 // JSONReactorLog lets you log as json lines.
 type JSONReactorLog struct {
-	Host      string  `json:",omitempty"`
-	Label     string  `json:",omitempty"`
-	Hash      string  `json:",omitempty"`
-	Pid       int     `json:",omitempty"`
-	RID       uint64  `json:",omitempty"`
-	TID       uint64  `json:",omitempty"`
-	Line      uint64  // Do not omit line number on line 0
-	Output    string  `json:",omitempty"`
-	Status    string  `json:",omitempty"`
-	Error     string  `json:",omitempty"`
-	Elapse    float64 `json:",omitempty"`
-	Timestamp int64   `json:",omitempty"`
+	Host       string  `json:",omitempty"`
+	Label      string  `json:",omitempty"`
+	Hash       string  `json:",omitempty"`
+	Pid        int     `json:",omitempty"`
+	RID        uint64  `json:",omitempty"`
+	TID        uint64  `json:",omitempty"`
+	StepN      int     `json:"Step,omitempty"`
+	Line       uint64  // Do not omit line number on line 0
+	Output     string  `json:",omitempty"`
+	StreamName string  `json:"Stream,omitempty"`
+	Status     string  `json:",omitempty"`
+	Error      string  `json:",omitempty"`
+	Elapse     float64 `json:",omitempty"`
+	Timestamp  int64   `json:",omitempty"`
+	*reactorlog.Usage
+	usage     *reactorlog.Usage
 	st        time.Time
 	w         strings.Builder
 	logStream lib.LogStream
+	redactor  *reactorlog.Redactor
+	routes    map[string]lib.LogStream
+	streams   []*streamWriter
 
 	initialized bool
 
@@ -75,6 +85,47 @@ func (rl *JSONReactorLog) SetHash(value string) {
 	rl.Hash = value
 }
 
+// SetRedactor defines the rules to hide sensitive values of the current execution
+func (rl *JSONReactorLog) SetRedactor(rd *reactorlog.Redactor) {
+	rl.Lock()
+	defer rl.Unlock()
+	rl.redactor = rd
+}
+
+// redact hides the secrets of the configuration and the sensitive values
+// of the current execution
+func (rl *JSONReactorLog) redact(s string) string {
+	return rl.redactor.Redact(lib.RedactSecrets(s))
+}
+
+// SetUsage keeps the usage of the process for the END line
+func (rl *JSONReactorLog) SetUsage(u reactorlog.Usage) {
+	rl.Lock()
+	defer rl.Unlock()
+	rl.usage = &u
+}
+
+// Step returns the log of the step n of the execution, with the same TID. The
+// step must be finished with its own Done.
+func (rl *JSONReactorLog) Step(n int) reactorlog.ReactorLog {
+	rl.Lock()
+	defer rl.Unlock()
+	s := NewJSONReactorLog(rl.logStream, rl.Host, rl.RID, rl.TID)
+	s.Label = rl.Label
+	s.Hash = rl.Hash
+	s.StepN = n
+	s.redactor = rl.redactor
+	s.routes = maps.Clone(rl.routes)
+	return s
+}
+
+// StepNumber returns the step of the execution, 0 if it's not a step
+func (rl *JSONReactorLog) StepNumber() int {
+	rl.Lock()
+	defer rl.Unlock()
+	return rl.StepN
+}
+
 // Write will be called by the reactor and this bytes will be sent to the general log channel
 func (rl *JSONReactorLog) Write(b []byte) (int, error) {
 	rl.Lock()
@@ -112,6 +163,9 @@ func (rl *JSONReactorLog) Start(pid int, s string) {
 	rl.w.WriteString(s)
 	rl.printJSON()
 	rl.Status = "RUN"
+	for _, sw := range rl.streams {
+		sw.flush(false)
+	}
 }
 
 // Done write in the logs the elapse time for the current execution
@@ -121,27 +175,36 @@ func (rl *JSONReactorLog) Done(err error) {
 	if !rl.initialized {
 		rl.handleWriteBytes(rl.buff.Bytes()) // It was never Started
 	}
+	for _, sw := range rl.streams {
+		sw.flush(true)
+	}
 
 	rl.Status = "END"
 	if err != nil {
-		rl.Error = err.Error()
+		rl.Error = rl.redact(err.Error())
 	}
 	// https://github.com/golang/go/issues/5491#issuecomment-66079585
 	rl.Elapse = time.Since(rl.st).Seconds()
+	rl.Usage = rl.usage
 	rl.printJSON()
 	rl.reset()
 }
 
 func (rl *JSONReactorLog) printJSON() {
-	rl.Output = rl.w.String()
+	output := rl.w.String()
 	rl.w.Reset()
+	rl.printLine(rl.logStream, output)
+}
+
+// printLine sends the line with the output to the logstream
+func (rl *JSONReactorLog) printLine(ls lib.LogStream, output string) {
+	rl.Output = rl.redact(output)
 
 	b, err := json.Marshal(rl)
 	if err != nil {
 		log.Printf("INTERNAL ERROR: %s", err.Error())
 		return
 	}
-	ls := rl.logStream
 	if ls != nil {
 		ls.Send(b)
 	}
@@ -156,13 +219,20 @@ func (rl *JSONReactorLog) reset() {
 	rl.Pid = 0
 	rl.RID = 0
 	rl.TID = 0
+	rl.StepN = 0
 	rl.Line = 0
 	rl.Output = ""
 	rl.Status = ""
 	rl.Error = ""
 	rl.Elapse = 0
 	rl.Timestamp = 0
+	rl.Usage = nil
+	rl.usage = nil
 	rl.logStream = nil
+	rl.redactor = nil
+	rl.routes = nil
+	rl.initialized = false
+	rl.streams = nil
 	rl.w.Reset()
 	rl.buff.Reset()
 	jsonReactorLogPool.Put(rl)
@@ -0,0 +1,73 @@
+package jsonreactorlog
+
+import (
+	"encoding/json"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/gabrielperezs/goreactor/reactorlog"
+	"github.com/stretchr/testify/assert"
+)
+
+type logStream struct {
+	lines []map[string]any
+}
+
+func (ls *logStream) Send(b []byte) {
+	var m map[string]any
+	json.Unmarshal(b, &m)
+	ls.lines = append(ls.lines, m)
+}
+
+func (ls *logStream) Exit() {}
+
+func TestRedactCmdOutputAndError(t *testing.T) {
+	t.Setenv("GOREACTOR_TEST_API_KEY", "key-from-env")
+
+	redaction := &reactorlog.Redaction{
+		Regexps: []*regexp.Regexp{regexp.MustCompile(`password=\S+`)},
+		Paths:   []string{"$.token"},
+		Env:     []string{"GOREACTOR_TEST_API_KEY", "DB_PASS"},
+	}
+	body := []byte(`{"token":"tk-123","name":"job"}`)
+
+	ls := &logStream{}
+	rl := NewJSONReactorLog(ls, "host", 1, 1)
+	rl.SetRedactor(redaction.Redactor(body, []string{"DB_PASS=db-secret"}))
+
+	rl.Start(10, "/bin/job --token tk-123 password=hunter2 job")
+	rl.Write([]byte("using key-from-env\nconnecting with db-secret\n"))
+	rl.Done(errors.New("failed with tk-123"))
+
+	assert.Equal(t, 4, len(ls.lines))
+	assert.Equal(t, "/bin/job --token [REDACTED] [REDACTED] job", ls.lines[0]["Output"])
+	assert.Equal(t, "using [REDACTED]", ls.lines[1]["Output"])
+	assert.Equal(t, "connecting with [REDACTED]", ls.lines[2]["Output"])
+	assert.Equal(t, "failed with [REDACTED]", ls.lines[3]["Error"])
+}
+
+func TestUsageInEndLine(t *testing.T) {
+	ls := &logStream{}
+	rl := NewJSONReactorLog(ls, "host", 1, 1)
+	rl.Start(10, "/bin/job")
+	rl.SetUsage(reactorlog.Usage{ExitCode: 0, UserTime: 0.5, MaxRSS: 2048, StdoutBytes: 6})
+	rl.Write([]byte("output\n"))
+	rl.Done(nil)
+
+	assert.Equal(t, 3, len(ls.lines))
+	assert.NotContains(t, ls.lines[1], "ExitCode")
+	end := ls.lines[2]
+	assert.Equal(t, "END", end["Status"])
+	assert.Equal(t, float64(0), end["ExitCode"])
+	assert.Equal(t, 0.5, end["UserTime"])
+	assert.Equal(t, float64(2048), end["MaxRSS"])
+	assert.Equal(t, float64(6), end["StdoutBytes"])
+	assert.NotContains(t, end, "Signal")
+	assert.NotContains(t, end, "TimedOut")
+
+	// The log is reused from the pool without the usage
+	rl = NewJSONReactorLog(ls, "host", 1, 2)
+	rl.Done(nil)
+	assert.NotContains(t, ls.lines[3], "ExitCode")
+}
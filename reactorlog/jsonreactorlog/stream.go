@@ -0,0 +1,90 @@
+package jsonreactorlog
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/gabrielperezs/goreactor/lib"
+)
+
+// streamWriter has the line buffer of one stream of the process, the lines
+// are logged with the name of the stream
+type streamWriter struct {
+	rl        *JSONReactorLog
+	name      string
+	logStream lib.LogStream
+	discard   bool
+	line      bytes.Buffer
+	pending   [][]byte // Lines written before Start
+}
+
+func (sw *streamWriter) Write(b []byte) (int, error) {
+	sw.rl.Lock()
+	defer sw.rl.Unlock()
+	if sw.discard {
+		return len(b), nil
+	}
+
+	for _, c := range b {
+		if c != newLine[0] {
+			sw.line.WriteByte(c)
+			continue
+		}
+		if !sw.rl.initialized {
+			sw.pending = append(sw.pending, bytes.Clone(sw.line.Bytes()))
+		} else {
+			sw.rl.printStreamLine(sw, sw.line.Bytes())
+		}
+		sw.line.Reset()
+	}
+	return len(b), nil
+}
+
+// flush logs the lines written before Start, and the last line without
+// new line if it's the end
+func (sw *streamWriter) flush(end bool) {
+	for _, l := range sw.pending {
+		sw.rl.printStreamLine(sw, l)
+	}
+	sw.pending = nil
+	if end && sw.line.Len() > 0 {
+		sw.rl.printStreamLine(sw, sw.line.Bytes())
+		sw.line.Reset()
+	}
+}
+
+// RouteStream sends the lines of the stream to other logstream, or discards
+// them if the logstream is nil
+func (rl *JSONReactorLog) RouteStream(name string, ls lib.LogStream) {
+	rl.Lock()
+	defer rl.Unlock()
+	if rl.routes == nil {
+		rl.routes = make(map[string]lib.LogStream)
+	}
+	rl.routes[name] = ls
+}
+
+// Stream returns the writer of the stream of the process, every stream has
+// its own lines
+func (rl *JSONReactorLog) Stream(name string) io.Writer {
+	rl.Lock()
+	defer rl.Unlock()
+	sw := &streamWriter{
+		rl:        rl,
+		name:      name,
+		logStream: rl.logStream,
+	}
+	if ls, ok := rl.routes[name]; ok {
+		sw.logStream = ls
+		sw.discard = ls == nil
+	}
+	rl.streams = append(rl.streams, sw)
+	return sw
+}
+
+// printStreamLine must be called with the lock
+func (rl *JSONReactorLog) printStreamLine(sw *streamWriter, line []byte) {
+	rl.StreamName = sw.name
+	rl.printLine(sw.logStream, string(line))
+	rl.StreamName = ""
+}
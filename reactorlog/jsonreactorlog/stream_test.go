@@ -0,0 +1,63 @@
+package jsonreactorlog
+
+import (
+	"testing"
+
+	"github.com/gabrielperezs/goreactor/reactorlog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamLines(t *testing.T) {
+	ls := &logStream{}
+	rl := NewJSONReactorLog(ls, "host", 1, 1)
+	stdout := rl.Stream(reactorlog.StreamStdout)
+	stderr := rl.Stream(reactorlog.StreamStderr)
+
+	// Written by the process before Start
+	stdout.Write([]byte("early\n"))
+	rl.Start(10, "/bin/job")
+
+	stdout.Write([]byte("first "))
+	stderr.Write([]byte("warning\n"))
+	stdout.Write([]byte("line\nlast"))
+	rl.Write([]byte("goreactor message\n"))
+	rl.Done(nil)
+
+	var lines [][2]any
+	for _, l := range ls.lines {
+		lines = append(lines, [2]any{l["Stream"], l["Output"]})
+	}
+	assert.Equal(t, [][2]any{
+		{nil, "/bin/job"},
+		{"stdout", "early"},
+		{"stderr", "warning"},
+		{"stdout", "first line"},
+		{nil, "goreactor message"},
+		{"stdout", "last"},
+		{nil, nil},
+	}, lines)
+}
+
+func TestStreamRoutes(t *testing.T) {
+	ls := &logStream{}
+	errors := &logStream{}
+	rl := NewJSONReactorLog(ls, "host", 1, 1)
+	rl.RouteStream(reactorlog.StreamStdout, nil)
+	rl.RouteStream(reactorlog.StreamStderr, errors)
+
+	step := rl.Step(1)
+	rl.Done(nil)
+
+	step.Start(10, "/bin/job")
+	step.(reactorlog.StreamLog).Stream(reactorlog.StreamStdout).Write([]byte("discarded\n"))
+	step.(reactorlog.StreamLog).Stream(reactorlog.StreamStderr).Write([]byte("failed\n"))
+	step.Done(nil)
+
+	assert.Equal(t, 3, len(ls.lines))
+	assert.Equal(t, "/bin/job", ls.lines[1]["Output"])
+	assert.Equal(t, "END", ls.lines[2]["Status"])
+	assert.Equal(t, 1, len(errors.lines))
+	assert.Equal(t, "failed", errors.lines[0]["Output"])
+	assert.Equal(t, "stderr", errors.lines[0]["Stream"])
+	assert.Equal(t, float64(1), errors.lines[0]["Step"])
+}
@@ -1,11 +1,15 @@
 package noopreactorlog
 
+import "github.com/gabrielperezs/goreactor/reactorlog"
+
 type NoopReactorLog struct{}
 
-func (NoopReactorLog) Start(pid int, s string) {}
-func (NoopReactorLog) SetLabel(string)         {}
-func (NoopReactorLog) SetHash(string)          {}
-func (NoopReactorLog) Done(error)              {}
+func (NoopReactorLog) Start(pid int, s string)          {}
+func (NoopReactorLog) SetLabel(string)                  {}
+func (NoopReactorLog) SetHash(string)                   {}
+func (NoopReactorLog) SetRedactor(*reactorlog.Redactor) {}
+func (NoopReactorLog) Done(error)                       {}
+func (l NoopReactorLog) Step(int) reactorlog.ReactorLog { return l }
 func (NoopReactorLog) Write(b []byte) (int, error) {
 	return len(b), nil
 }
@@ -5,5 +5,7 @@ type ReactorLog interface {
 	Start(pid int, s string)
 	SetLabel(string)
 	SetHash(string)
+	SetRedactor(*Redactor)
+	Step(n int) ReactorLog
 	Done(error)
 }
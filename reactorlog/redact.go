@@ -0,0 +1,103 @@
+package reactorlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/savaki/jq"
+)
+
+// RedactedValue replaces the secrets and the sensitive values in the logs
+const RedactedValue = "[REDACTED]"
+
+// Redaction contains the rules of a reactor to hide sensitive values
+// from the logs
+type Redaction struct {
+	Regexps []*regexp.Regexp // Matches are replaced
+	Paths   []string         // Values of these jq paths in the message are replaced
+	Env     []string         // Values of these environment variables are replaced
+}
+
+// Empty returns true if there are no rules defined
+func (r *Redaction) Empty() bool {
+	return r == nil || (len(r.Regexps) == 0 && len(r.Paths) == 0 && len(r.Env) == 0)
+}
+
+// Redactor builds the Redactor for one message. environ is the list of
+// NAME=value variables passed to the command, they have precedence over the
+// variables of the current process.
+func (r *Redaction) Redactor(body []byte, environ []string) *Redactor {
+	if r.Empty() {
+		return nil
+	}
+
+	rd := &Redactor{
+		re: r.Regexps,
+	}
+
+	for _, p := range r.Paths {
+		op, err := jq.Parse(strings.TrimPrefix(p, "$"))
+		if err != nil {
+			continue
+		}
+		value, err := op.Apply(body)
+		if err != nil {
+			continue
+		}
+		var values []string
+		if json.Unmarshal(value, &values) == nil {
+			rd.addValues(values...)
+			continue
+		}
+		rd.addValues(string(bytes.Trim(value, "\"")))
+	}
+
+	for _, name := range r.Env {
+		value, ok := os.LookupEnv(name)
+		for _, e := range environ {
+			if strings.HasPrefix(e, name+"=") {
+				value, ok = e[len(name)+1:], true // Last one wins, like in exec
+			}
+		}
+		if ok {
+			rd.addValues(value)
+		}
+	}
+
+	// Longest first, so a value containing another one is fully redacted
+	sort.Slice(rd.values, func(i, j int) bool { return len(rd.values[i]) > len(rd.values[j]) })
+	return rd
+}
+
+// Redactor hides the sensitive values of one execution
+type Redactor struct {
+	re     []*regexp.Regexp
+	values []string
+}
+
+func (rd *Redactor) addValues(values ...string) {
+	for _, v := range values {
+		if v == "" || v == "null" {
+			continue
+		}
+		rd.values = append(rd.values, v)
+	}
+}
+
+// Redact returns s without the sensitive values. A nil Redactor returns s.
+func (rd *Redactor) Redact(s string) string {
+	if rd == nil {
+		return s
+	}
+	for _, v := range rd.values {
+		s = strings.ReplaceAll(s, v, RedactedValue)
+	}
+	for _, re := range rd.re {
+		s = re.ReplaceAllString(s, RedactedValue)
+	}
+	return s
+}
@@ -0,0 +1,21 @@
+package reactorlog
+
+import "io"
+
+// Streams of the output of the process
+const (
+	StreamStdout = "stdout"
+	StreamStderr = "stderr"
+)
+
+// StepLog is implemented by the logs that know the step of the execution
+// they log, 0 if it's not a step
+type StepLog interface {
+	StepNumber() int
+}
+
+// StreamLog is implemented by the logs that keep the lines of every stream of
+// the process apart
+type StreamLog interface {
+	Stream(name string) io.Writer
+}
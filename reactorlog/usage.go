@@ -0,0 +1,20 @@
+package reactorlog
+
+// Usage is the result and the resources used by the process of an execution
+type Usage struct {
+	ExitCode    int     // -1 if the process was terminated by a signal
+	Signal      string  `json:",omitempty"`
+	TimedOut    bool    `json:",omitempty"` // Killed by the maximum time of the command
+	UserTime    float64 // Seconds
+	SysTime     float64 // Seconds
+	MaxRSS      int64   `json:",omitempty"` // Kilobytes
+	StdoutBytes int64
+	StderrBytes int64
+	Violations  []string `json:",omitempty"` // Limits of the cgroup reached by the process, like memory or pids
+}
+
+// UsageLog is implemented by the logs that record the usage of the process
+// in the end of the execution
+type UsageLog interface {
+	SetUsage(Usage)
+}